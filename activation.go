@@ -0,0 +1,31 @@
+package net
+
+import "math"
+
+// Activation functions that can be stored in NeuronGene.Activation. Nets built
+// with a custom Builder.ActivationFunc store ActSigmoid.
+const (
+	ActSigmoid = iota
+	ActTanh
+	ActSin
+	ActGaussian
+	ActAbs
+	ActLinear
+)
+
+var activationFuncs = map[int]func(float64) float64{
+	ActSigmoid:  sigmoid,
+	ActTanh:     math.Tanh,
+	ActSin:      math.Sin,
+	ActGaussian: gaussian,
+	ActAbs:      math.Abs,
+	ActLinear:   linear,
+}
+
+func gaussian(x float64) float64 {
+	return math.Exp(-x * x)
+}
+
+func linear(x float64) float64 {
+	return x
+}
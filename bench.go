@@ -0,0 +1,464 @@
+package net
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Task is a benchmark problem, a net of In inputs, Hidden hidden neurons of
+// Kind and Out outputs is evolved to minimize Fitness until Solved
+type Task struct {
+	Name            string
+	In, Hidden, Out int
+	Kind            int
+	Fitness         func(*Net) (float64, error)
+	Solved          func(*Net) (bool, error)
+}
+
+// XORTask is the classic xor, the output should have the sign of the xor of
+// two inputs of -1 or 1
+func XORTask() Task {
+	return ParityTask(2)
+}
+
+// ParityTask outputs 1 if an odd amount of the bits inputs are 1, -1 otherwise
+func ParityTask(bits int) Task {
+	var inputs, targets [][]float64
+	for i := 0; i < 1<<bits; i++ {
+		in := make([]float64, bits)
+		ones := 0
+		for b := range in {
+			in[b] = -1
+			if i&(1<<b) != 0 {
+				in[b] = 1
+				ones++
+			}
+		}
+		target := -1.0
+		if ones%2 == 1 {
+			target = 1
+		}
+		inputs = append(inputs, in)
+		targets = append(targets, []float64{target})
+	}
+	name := fmt.Sprintf("parity%d", bits)
+	if bits == 2 {
+		name = "xor"
+	}
+	return classifyTask(name, bits, 2*bits, inputs, targets)
+}
+
+// FunctionTask approximates sin on [-pi, pi], the input is scaled to [-1, 1].
+// It's solved once the mean squared error is below 0.01.
+func FunctionTask() Task {
+	var inputs, targets [][]float64
+	for i := 0; i <= 20; i++ {
+		x := -math.Pi + float64(i)*math.Pi/10
+		inputs = append(inputs, []float64{x / math.Pi})
+		targets = append(targets, []float64{math.Sin(x)})
+	}
+	fitness := func(n *Net) (float64, error) { return mse(n, inputs, targets) }
+	return Task{
+		Name:    "sin",
+		In:      1,
+		Hidden:  5,
+		Out:     1,
+		Fitness: fitness,
+		Solved: func(n *Net) (bool, error) {
+			err, e := fitness(n)
+			return err < 0.01, e
+		},
+	}
+}
+
+// RecallTask shows a bit of -1 or 1, waits delay steps and then cues the net
+// to output the bit. The net runs in step mode with LSTM hidden neurons.
+func RecallTask(delay int) Task {
+	run := func(n *Net, bit float64) (float64, error) {
+		n.Reset()
+		if _, err := n.Step([]float64{bit, 0}); err != nil {
+			return 0, err
+		}
+		for i := 0; i < delay; i++ {
+			if _, err := n.Step([]float64{0, 0}); err != nil {
+				return 0, err
+			}
+		}
+		out, err := n.Step([]float64{0, 1})
+		if err != nil {
+			return 0, err
+		}
+		return out[0], nil
+	}
+	return Task{
+		Name:   fmt.Sprintf("recall%d", delay),
+		In:     2,
+		Hidden: 4,
+		Out:    1,
+		Kind:   KindLSTM,
+		Fitness: func(n *Net) (float64, error) {
+			var sum float64
+			for _, bit := range []float64{-1, 1} {
+				out, err := run(n, bit)
+				if err != nil {
+					return 0, err
+				}
+				sum += (out - bit) * (out - bit)
+			}
+			return sum / 2, nil
+		},
+		Solved: func(n *Net) (bool, error) {
+			for _, bit := range []float64{-1, 1} {
+				out, err := run(n, bit)
+				if err != nil || out*bit <= 0 {
+					return false, err
+				}
+			}
+			return true, nil
+		},
+	}
+}
+
+// CartPoleTask balances a single pole with velocity inputs for 1000 steps
+func CartPoleTask() Task {
+	// a single pole is always valid
+	r, _ := CartPoleRunner(1, true)
+	return Task{
+		Name:    "cartpole",
+		In:      4,
+		Hidden:  4,
+		Out:     1,
+		Fitness: r.Fitness,
+		Solved: func(n *Net) (bool, error) {
+			fit, err := r.Fitness(n)
+			return fit <= -float64(r.MaxSteps), err
+		},
+	}
+}
+
+// classifyTask minimizes the squared error, it's solved once every output has
+// the sign of its target
+func classifyTask(name string, in, hidden int, inputs, targets [][]float64) Task {
+	return Task{
+		Name:    name,
+		In:      in,
+		Hidden:  hidden,
+		Out:     len(targets[0]),
+		Fitness: func(n *Net) (float64, error) { return mse(n, inputs, targets) },
+		Solved: func(n *Net) (bool, error) {
+			for i, in := range inputs {
+				out, err := n.Eval(in)
+				if err != nil {
+					return false, err
+				}
+				for j := range out {
+					if out[j]*targets[i][j] <= 0 {
+						return false, nil
+					}
+				}
+			}
+			return true, nil
+		},
+	}
+}
+
+func mse(n *Net, inputs, targets [][]float64) (float64, error) {
+	var sum float64
+	var count int
+	for i, in := range inputs {
+		out, err := n.Eval(in)
+		if err != nil {
+			return 0, err
+		}
+		if len(out) != len(targets[i]) {
+			return 0, fmt.Errorf("output size %d, expected %d", len(out), len(targets[i]))
+		}
+		for j := range out {
+			d := out[j] - targets[i][j]
+			sum += d * d
+			count++
+		}
+	}
+	return sum / float64(count), nil
+}
+
+// Tasks returns the benchmark tasks by name
+func Tasks() map[string]Task {
+	tasks := map[string]Task{}
+	for _, t := range []Task{
+		XORTask(),
+		ParityTask(3),
+		ParityTask(4),
+		FunctionTask(),
+		RecallTask(3),
+		CartPoleTask(),
+	} {
+		tasks[t.Name] = t
+	}
+	return tasks
+}
+
+// Mutations are the mutation operators a Benchmark can use, by name
+var Mutations = map[string]func(*DNA, *rand.Rand){
+	"mutateall": (*DNA).MutateAll,
+	"mutate":    func(dna *DNA, rng *rand.Rand) { dna.Mutate(rng) },
+}
+
+// Crossovers are the crossover operators a Benchmark can use, by name
+var Crossovers = map[string]func(a, b DNA, rng *rand.Rand) DNA{
+	"mate": func(a, b DNA, rng *rand.Rand) DNA { return a.Mate(b, rng) },
+	"crossover": func(a, b DNA, rng *rand.Rand) DNA {
+		child := a.Clone()
+		child.Crossover(b.Clone(), rng)
+		return child
+	},
+}
+
+// Operators is a pair of a mutation and a crossover by name
+type Operators struct {
+	Mutation  string
+	Crossover string
+}
+
+// Benchmark evolves every task Trials times with every pair of Operators,
+// trial i uses Seed+i, so two runs of the same code give the same results and
+// runs of different code can be compared. DNA.Mutate draws from the global
+// random numbers, so trials using it aren't reproducible.
+type Benchmark struct {
+	Tasks       []Task
+	Operators   []Operators
+	Trials      int
+	PopSize     int
+	Generations int
+	Workers     int
+	Seed        int64
+	// Configure is called on every Evolution before it runs, after the
+	// Operators are set, to try other mutations, selectors or rates
+	Configure func(*Evolution)
+}
+
+// DefaultOperators are the defaults of Evolution and the original
+// DNA.Mutate and DNA.Crossover
+var DefaultOperators = []Operators{
+	{Mutation: "mutateall", Crossover: "mate"},
+	{Mutation: "mutate", Crossover: "crossover"},
+}
+
+// NewBenchmark returns a Benchmark with default settings
+func NewBenchmark(tasks ...Task) *Benchmark {
+	return &Benchmark{
+		Tasks:       tasks,
+		Operators:   append([]Operators(nil), DefaultOperators...),
+		Trials:      10,
+		PopSize:     100,
+		Generations: 200,
+		Seed:        1,
+	}
+}
+
+// BenchmarkResult is the outcome of a Benchmark, Label is free to identify the
+// run, for example with a commit hash
+type BenchmarkResult struct {
+	Label       string
+	Date        time.Time
+	Trials      int
+	PopSize     int
+	Generations int
+	Tasks       []TaskResult
+}
+
+// TaskResult summarizes the trials of a task. Generations is the mean amount
+// of generations the solved trials needed, Neurons and Synapses the mean size
+// of the best net of every trial.
+type TaskResult struct {
+	Task        string
+	Mutation    string `json:",omitempty"`
+	Crossover   string `json:",omitempty"`
+	SuccessRate float64
+	Generations float64
+	Fitness     float64
+	Neurons     float64
+	Synapses    float64
+	Runs        []TrialResult
+}
+
+// Key identifies the task and operators. Results from before the operators
+// were recorded used the defaults of Evolution.
+func (t TaskResult) Key() string {
+	ops := Operators{t.Mutation, t.Crossover}
+	if ops == (Operators{}) {
+		ops = DefaultOperators[0]
+	}
+	return t.Task + " " + ops.Mutation + "/" + ops.Crossover
+}
+
+// TrialResult is the outcome of one evolution run
+type TrialResult struct {
+	Seed        int64
+	Solved      bool
+	Generations int
+	Fitness     float64
+	Neurons     int
+	Synapses    int
+}
+
+// Run runs every trial of every task with every pair of operators
+func (b *Benchmark) Run() (BenchmarkResult, error) {
+	res := BenchmarkResult{
+		Date:        time.Now().UTC(),
+		Trials:      b.Trials,
+		PopSize:     b.PopSize,
+		Generations: b.Generations,
+	}
+	for _, ops := range b.Operators {
+		if Mutations[ops.Mutation] == nil {
+			return res, fmt.Errorf("unknown mutation %q", ops.Mutation)
+		}
+		if Crossovers[ops.Crossover] == nil {
+			return res, fmt.Errorf("unknown crossover %q", ops.Crossover)
+		}
+	}
+	for _, ops := range b.Operators {
+		for _, task := range b.Tasks {
+			tr, err := b.runTask(task, ops)
+			if err != nil {
+				return res, err
+			}
+			res.Tasks = append(res.Tasks, tr)
+		}
+	}
+	return res, nil
+}
+
+// runTask runs the trials of a task with ops
+func (b *Benchmark) runTask(task Task, ops Operators) (TaskResult, error) {
+	tr := TaskResult{Task: task.Name, Mutation: ops.Mutation, Crossover: ops.Crossover}
+	var solved int
+	for i := 0; i < b.Trials; i++ {
+		run, err := b.trial(task, ops, b.Seed+int64(i))
+		if err != nil {
+			return tr, fmt.Errorf("%s with %s/%s: %w", task.Name, ops.Mutation, ops.Crossover, err)
+		}
+		tr.Runs = append(tr.Runs, run)
+		if run.Solved {
+			solved++
+			tr.Generations += float64(run.Generations)
+		}
+		tr.Fitness += run.Fitness
+		tr.Neurons += float64(run.Neurons)
+		tr.Synapses += float64(run.Synapses)
+	}
+	if solved > 0 {
+		tr.Generations /= float64(solved)
+	}
+	if b.Trials > 0 {
+		tr.SuccessRate = float64(solved) / float64(b.Trials)
+		tr.Fitness /= float64(b.Trials)
+		tr.Neurons /= float64(b.Trials)
+		tr.Synapses /= float64(b.Trials)
+	}
+	return tr, nil
+}
+
+func (b *Benchmark) trial(task Task, ops Operators, seed int64) (TrialResult, error) {
+	run := TrialResult{Seed: seed}
+	e := NewEvolution(func(dna DNA) (float64, error) {
+		n, err := DNAToNet(dna)
+		if err != nil {
+			return 0, err
+		}
+		fit, err := task.Fitness(n)
+		if math.IsNaN(fit) {
+			fit = math.Inf(1)
+		}
+		return fit, err
+	})
+	e.PopSize = b.PopSize
+	e.Generations = b.Generations
+	e.Workers = b.Workers
+	e.Seed = seed
+	e.Mutate = Mutations[ops.Mutation]
+	e.Cross = Crossovers[ops.Crossover]
+	var solveErr error
+	e.EarlyStop = func(e *Evolution) bool {
+		n, err := DNAToNet(e.Best.DNA)
+		if err != nil {
+			solveErr = err
+			return true
+		}
+		run.Solved, solveErr = task.Solved(n)
+		return run.Solved || solveErr != nil
+	}
+	if b.Configure != nil {
+		b.Configure(e)
+	}
+	err := e.Minimize(func(rng *rand.Rand) DNA {
+		r := func() float64 { return rng.Float64()*2 - 1 }
+		n, _ := NewBuilder().
+			Size(task.In, task.Hidden, task.Out).
+			HiddenKind(task.Kind).
+			WeightFunc(r).
+			BiasFunc(r).
+			Build()
+		return NetToDna(n)
+	})
+	if err != nil {
+		return run, err
+	}
+	if solveErr != nil {
+		return run, solveErr
+	}
+	if !run.Solved {
+		// EarlyStop isn't called after the last generation
+		n, err := DNAToNet(e.Best.DNA)
+		if err != nil {
+			return run, err
+		}
+		if run.Solved, err = task.Solved(n); err != nil {
+			return run, err
+		}
+	}
+	run.Generations = e.Generation
+	run.Fitness = e.Best.Fitness
+	run.Neurons = len(e.Best.DNA.Neurons)
+	run.Synapses = len(e.Best.DNA.SynapseMap)
+	return run, nil
+}
+
+// WriteJSON writes the result as json
+func (r BenchmarkResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(r)
+}
+
+// ReadBenchmarkResult reads a result written by WriteJSON
+func ReadBenchmarkResult(r io.Reader) (BenchmarkResult, error) {
+	var res BenchmarkResult
+	err := json.NewDecoder(r).Decode(&res)
+	return res, err
+}
+
+// Regressions returns a line for every task that got a lower success rate
+// than in baseline, by more than tolerance
+func (r BenchmarkResult) Regressions(baseline BenchmarkResult, tolerance float64) []string {
+	base := make(map[string]TaskResult)
+	for _, t := range baseline.Tasks {
+		base[t.Key()] = t
+	}
+	var regressions []string
+	for _, t := range r.Tasks {
+		b, ok := base[t.Key()]
+		if !ok {
+			continue
+		}
+		if t.SuccessRate < b.SuccessRate-tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s: success rate %.2f, baseline %.2f", t.Key(), t.SuccessRate, b.SuccessRate))
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,83 @@
+package net
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTasks(t *testing.T) {
+	for name, task := range Tasks() {
+		assert.Equal(t, name, task.Name)
+		n, err := NewBuilder().Size(task.In, task.Hidden, task.Out).HiddenKind(task.Kind).Build()
+		require.NoError(t, err)
+		fit, err := task.Fitness(n)
+		require.NoError(t, err, name)
+		assert.GreaterOrEqual(t, fit, -1000.0, name)
+		_, err = task.Solved(n)
+		require.NoError(t, err, name)
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	b := NewBenchmark(XORTask(), RecallTask(2))
+	assert.Equal(t, DefaultOperators, b.Operators)
+	// DNA.Mutate isn't reproducible, see TestBenchmark_operators
+	b.Operators = b.Operators[:1]
+	b.Trials = 2
+	b.PopSize = 30
+	b.Generations = 20
+	res, err := b.Run()
+	require.NoError(t, err)
+	require.Len(t, res.Tasks, 2)
+	for _, tr := range res.Tasks {
+		require.Len(t, tr.Runs, 2)
+		assert.Equal(t, []int64{1, 2}, []int64{tr.Runs[0].Seed, tr.Runs[1].Seed})
+		for _, run := range tr.Runs {
+			assert.LessOrEqual(t, run.Generations, 20)
+			assert.Greater(t, run.Synapses, 0)
+		}
+	}
+
+	// same seeds, same results
+	again, err := b.Run()
+	require.NoError(t, err)
+	assert.Equal(t, res.Tasks, again.Tasks)
+
+	var buf bytes.Buffer
+	require.NoError(t, res.WriteJSON(&buf))
+	read, err := ReadBenchmarkResult(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, res.Tasks, read.Tasks)
+
+	worse := read
+	worse.Tasks = append([]TaskResult(nil), read.Tasks...)
+	worse.Tasks[0].SuccessRate = res.Tasks[0].SuccessRate - 0.5
+	assert.Empty(t, res.Regressions(res, 0))
+	assert.Len(t, worse.Regressions(res, 0.1), 1)
+}
+
+func TestBenchmark_operators(t *testing.T) {
+	b := NewBenchmark(XORTask())
+	b.Trials = 1
+	b.PopSize = 10
+	b.Generations = 3
+	res, err := b.Run()
+	require.NoError(t, err)
+	require.Len(t, res.Tasks, len(DefaultOperators))
+	for i, ops := range DefaultOperators {
+		assert.Equal(t, ops, Operators{res.Tasks[i].Mutation, res.Tasks[i].Crossover})
+		assert.Equal(t, "xor "+ops.Mutation+"/"+ops.Crossover, res.Tasks[i].Key())
+	}
+	// results without operators compare with the defaults
+	assert.Equal(t, res.Tasks[0].Key(), TaskResult{Task: "xor"}.Key())
+
+	b.Operators = []Operators{{Mutation: "nope", Crossover: "mate"}}
+	_, err = b.Run()
+	assert.Error(t, err)
+	b.Operators = []Operators{{Mutation: "mutate", Crossover: "nope"}}
+	_, err = b.Run()
+	assert.Error(t, err)
+}
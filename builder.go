@@ -16,6 +16,12 @@ type Builder struct {
 	outSize    int
 	hiddenSize int
 
+	float32    bool
+	ticks      int
+	dt         float64
+	hiddenKind int
+	tau        float64
+
 	dna []float64
 }
 
@@ -47,6 +53,8 @@ func defaultBuilder() *Builder {
 		activationFunc: defaultActivationFunc,
 		weightFunc:     defaultWeightFunc,
 		biasFunc:       defaultBiasFunc,
+		dt:             defaultTimeStep,
+		tau:            defaultTau,
 	}
 }
 
@@ -55,14 +63,34 @@ func (b *Builder) Build() (*Net, error) {
 	if b == nil {
 		b = defaultBuilder()
 	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	n := newNet(b)
+	return n, nil
+}
+
+// BuildDense builds the network as a Dense, a matrix backed net that
+// evaluates faster than the neuron graph
+func (b *Builder) BuildDense() (*Dense, error) {
+	if b == nil {
+		b = defaultBuilder()
+	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	return newDense(b), nil
+}
+
+func (b *Builder) validate() error {
 	if b.inSize < 1 {
-		return nil, fmt.Errorf("inputSize should be > 1")
+		return fmt.Errorf("inputSize should be > 1")
 	}
 	if b.outSize < 1 {
-		return nil, fmt.Errorf("outputSize should be > 1")
+		return fmt.Errorf("outputSize should be > 1")
 	}
 	if b.hiddenSize < 1 {
-		return nil, fmt.Errorf("amountOfHiddenNeurons should be > 1")
+		return fmt.Errorf("amountOfHiddenNeurons should be > 1")
 	}
 	if b.activationFunc == nil {
 		b.activationFunc = defaultActivationFunc
@@ -73,8 +101,7 @@ func (b *Builder) Build() (*Net, error) {
 	if b.biasFunc == nil {
 		b.biasFunc = defaultBiasFunc
 	}
-	n := newNet(b)
-	return n, nil
+	return nil
 }
 
 // ActivationFunc sets the activation func the neurons will use
@@ -89,7 +116,8 @@ func (b *Builder) WeightFunc(f func() float64) *Builder {
 	return b
 }
 
-// WeightFunc sets the func used to initialise the neuron's bias
+// BiasFunc sets the func used to initialise the neuron's bias, by default a
+// random bias in [-1, 1)
 func (b *Builder) BiasFunc(f func() float64) *Builder {
 	b.biasFunc = f
 	return b
@@ -102,3 +130,35 @@ func (b *Builder) Size(inputSize, hiddenSize, outputSize int) *Builder {
 	b.outSize = outputSize
 	return b
 }
+
+// Float32 makes BuildDense return a net that evaluates with float32 arithmetic
+func (b *Builder) Float32() *Builder {
+	b.float32 = true
+	return b
+}
+
+// StepMode makes the net evaluate synchronously, every Eval runs ticks Steps.
+// See Net.StepMode
+func (b *Builder) StepMode(ticks int) *Builder {
+	b.ticks = ticks
+	return b
+}
+
+// HiddenKind sets the kind of the hidden neurons, KindStandard, KindCTRNN,
+// KindGRU or KindLSTM. Gate parameters are initialised with the WeightFunc
+func (b *Builder) HiddenKind(kind int) *Builder {
+	b.hiddenKind = kind
+	return b
+}
+
+// Tau sets the time constant of CTRNN neurons
+func (b *Builder) Tau(tau float64) *Builder {
+	b.tau = tau
+	return b
+}
+
+// TimeStep sets the dt CTRNN neurons are integrated with
+func (b *Builder) TimeStep(dt float64) *Builder {
+	b.dt = dt
+	return b
+}
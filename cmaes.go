@@ -0,0 +1,274 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// CMAES is the covariance matrix adaptation evolution strategy, following
+// Hansen's "The CMA Evolution Strategy: A Tutorial". It adapts a full
+// covariance matrix, which costs O(n²) memory and O(n³) per eigen
+// decomposition, so it's meant for nets with up to a few hundred parameters.
+// Like the rest of the package it minimizes.
+type CMAES struct {
+	// Sigma is the initial step size
+	Sigma float64
+	// Lambda is the population size, 0 uses 4 + 3 ln(n)
+	Lambda      int
+	Generations int
+	// Workers is the amount of fitness evaluations run in parallel, 0 uses
+	// GOMAXPROCS
+	Workers int
+	Seed    int64
+
+	// Callback is called after every generation, EarlyStop before every
+	// generation
+	Callback  func(*CMAES)
+	EarlyStop func(*CMAES) bool
+
+	Mean        []float64
+	Best        []float64
+	BestFitness float64
+	Generation  int
+}
+
+// NewCMAES returns a CMAES with default settings
+func NewCMAES() *CMAES {
+	return &CMAES{
+		Sigma:       0.5,
+		Generations: 100,
+		Seed:        1,
+	}
+}
+
+// Minimize optimizes f starting from x0
+func (es *CMAES) Minimize(x0 []float64, f func([]float64) (float64, error)) error {
+	n := len(x0)
+	if n == 0 {
+		return fmt.Errorf("nothing to optimize")
+	}
+	rng := rand.New(rand.NewSource(es.Seed))
+	lambda := es.Lambda
+	if lambda < 2 {
+		lambda = 4 + int(3*math.Log(float64(n)))
+	}
+	mu := lambda / 2
+
+	{ // initial point
+		es.Mean = append([]float64(nil), x0...)
+		es.Best = append([]float64(nil), x0...)
+		es.Generation = 0
+		fit, err := f(x0)
+		if err != nil {
+			return err
+		}
+		es.BestFitness = fit
+	}
+
+	// strategy parameters
+	weights := make([]float64, mu)
+	var sumW, sumW2 float64
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sumW += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sumW
+		sumW2 += weights[i] * weights[i]
+	}
+	N := float64(n)
+	mueff := 1 / sumW2
+	cc := (4 + mueff/N) / (N + 4 + 2*mueff/N)
+	cs := (mueff + 2) / (N + mueff + 5)
+	c1 := 2 / ((N+1.3)*(N+1.3) + mueff)
+	cmu := math.Min(1-c1, 2*(mueff-2+1/mueff)/((N+2)*(N+2)+mueff))
+	damps := 1 + 2*math.Max(0, math.Sqrt((mueff-1)/(N+1))-1) + cs
+	chiN := math.Sqrt(N) * (1 - 1/(4*N) + 1/(21*N*N))
+
+	// dynamic state
+	sigma := es.Sigma
+	pc := make([]float64, n)
+	ps := make([]float64, n)
+	B := identity(n)
+	D := make([]float64, n)
+	for i := range D {
+		D[i] = 1
+	}
+	C := identity(n)
+	invSqrtC := identity(n)
+	var evals, eigenEval int
+
+	for es.Generation < es.Generations {
+		if es.EarlyStop != nil && es.EarlyStop(es) {
+			return nil
+		}
+
+		points := make([][]float64, lambda)
+		for k := range points {
+			z := make([]float64, n)
+			for i := range z {
+				z[i] = D[i] * rng.NormFloat64()
+			}
+			points[k] = make([]float64, n)
+			for i := range points[k] {
+				var y float64
+				for j := range z {
+					y += B[i][j] * z[j]
+				}
+				points[k][i] = es.Mean[i] + sigma*y
+			}
+		}
+		fits, err := evalPoints(points, es.Workers, f)
+		if err != nil {
+			return err
+		}
+		evals += lambda
+		idx := make([]int, lambda)
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(i, j int) bool { return fits[idx[i]] < fits[idx[j]] })
+		if fits[idx[0]] < es.BestFitness {
+			es.BestFitness = fits[idx[0]]
+			es.Best = append(es.Best[:0], points[idx[0]]...)
+		}
+
+		old := es.Mean
+		es.Mean = make([]float64, n)
+		for k := 0; k < mu; k++ {
+			for i := range es.Mean {
+				es.Mean[i] += weights[k] * points[idx[k]][i]
+			}
+		}
+		step := make([]float64, n)
+		for i := range step {
+			step[i] = (es.Mean[i] - old[i]) / sigma
+		}
+
+		{ // cumulation of the evolution paths
+			var psNorm float64
+			for i := range ps {
+				var v float64
+				for j := range step {
+					v += invSqrtC[i][j] * step[j]
+				}
+				ps[i] = (1-cs)*ps[i] + math.Sqrt(cs*(2-cs)*mueff)*v
+				psNorm += ps[i] * ps[i]
+			}
+			psNorm = math.Sqrt(psNorm)
+			var hsig float64
+			if psNorm/math.Sqrt(1-math.Pow(1-cs, 2*float64(evals)/float64(lambda)))/chiN < 1.4+2/(N+1) {
+				hsig = 1
+			}
+			for i := range pc {
+				pc[i] = (1-cc)*pc[i] + hsig*math.Sqrt(cc*(2-cc)*mueff)*step[i]
+			}
+
+			// adapt the covariance matrix
+			for i := range C {
+				for j := 0; j <= i; j++ {
+					rankMu := 0.0
+					for k := 0; k < mu; k++ {
+						x := points[idx[k]]
+						rankMu += weights[k] * (x[i] - old[i]) / sigma * (x[j] - old[j]) / sigma
+					}
+					C[i][j] = (1-c1-cmu)*C[i][j] +
+						c1*(pc[i]*pc[j]+(1-hsig)*cc*(2-cc)*C[i][j]) +
+						cmu*rankMu
+					C[j][i] = C[i][j]
+				}
+			}
+
+			// adapt the step size
+			sigma *= math.Exp((cs / damps) * (psNorm/chiN - 1))
+		}
+
+		if float64(evals-eigenEval) > float64(lambda)/(c1+cmu)/N/10 {
+			eigenEval = evals
+			var vals []float64
+			vals, B = eigenSym(C)
+			for i, v := range vals {
+				D[i] = math.Sqrt(math.Max(v, 1e-20))
+			}
+			for i := range invSqrtC {
+				for j := range invSqrtC[i] {
+					var v float64
+					for k := range D {
+						v += B[i][k] / D[k] * B[j][k]
+					}
+					invSqrtC[i][j] = v
+				}
+			}
+		}
+
+		es.Generation++
+		if es.Callback != nil {
+			es.Callback(es)
+		}
+	}
+	return nil
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+// eigenSym returns the eigenvalues and eigenvectors, as columns, of the
+// symmetric matrix a using Jacobi rotations
+func eigenSym(a [][]float64) (vals []float64, vecs [][]float64) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = append([]float64(nil), a[i]...)
+	}
+	vecs = identity(n)
+	for sweep := 0; sweep < 100; sweep++ {
+		var off float64
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(m[p][q]) < 1e-300 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := vecs[k][p], vecs[k][q]
+					vecs[k][p] = c*vkp - s*vkq
+					vecs[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	vals = make([]float64, n)
+	for i := range vals {
+		vals[i] = m[i][i]
+	}
+	return vals, vecs
+}
@@ -0,0 +1,148 @@
+// Command bench runs the benchmark tasks and compares the results against a
+// baseline, it exits with status 1 on a regression
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Wouterbeets/net"
+)
+
+var (
+	tasks       = flag.String("tasks", "all", "comma separated tasks to run, or all")
+	trials      = flag.Int("trials", 10, "seeded runs per task")
+	generations = flag.Int("generations", 200, "max generations per run")
+	pop         = flag.Int("pop", 100, "population size")
+	seed        = flag.Int64("seed", 1, "seed of the first run, run i uses seed+i")
+	workers     = flag.Int("workers", 0, "parallel evaluations, 0 uses all cpus")
+	label       = flag.String("label", "", "label stored in the result, e.g. a commit hash")
+	out         = flag.String("out", "", "write the result as json to this file")
+	baseline    = flag.String("baseline", "", "json result to compare against")
+	tolerance   = flag.Float64("tolerance", 0.1, "allowed drop in success rate against the baseline")
+	mutate      = flag.String("mutate", "", "mutation to run: mutateall or mutate, empty runs both default pairs")
+	cross       = flag.String("cross", "", "crossover to run: mate or crossover, empty runs both default pairs")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+func run() error {
+	selected, err := selectTasks(*tasks)
+	if err != nil {
+		return err
+	}
+	b := net.NewBenchmark(selected...)
+	b.Trials = *trials
+	b.Generations = *generations
+	b.PopSize = *pop
+	b.Seed = *seed
+	b.Workers = *workers
+	if *mutate != "" || *cross != "" {
+		ops := net.DefaultOperators[0]
+		if *mutate != "" {
+			ops.Mutation = *mutate
+		}
+		if *cross != "" {
+			ops.Crossover = *cross
+		}
+		b.Operators = []net.Operators{ops}
+	}
+	res, err := b.Run()
+	if err != nil {
+		return err
+	}
+	res.Label = *label
+
+	var base *net.BenchmarkResult
+	if *baseline != "" {
+		f, err := os.Open(*baseline)
+		if err != nil {
+			return err
+		}
+		r, err := net.ReadBenchmarkResult(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", *baseline, err)
+		}
+		base = &r
+	}
+	printTable(res, base)
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := res.WriteJSON(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if base != nil {
+		regressions := res.Regressions(*base, *tolerance)
+		for _, r := range regressions {
+			fmt.Println("regression:", r)
+		}
+		if len(regressions) > 0 {
+			os.Exit(1)
+		}
+	}
+	return nil
+}
+
+func selectTasks(names string) ([]net.Task, error) {
+	all := net.Tasks()
+	if names == "all" {
+		var keys []string
+		for k := range all {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		names = strings.Join(keys, ",")
+	}
+	var selected []net.Task
+	for _, name := range strings.Split(names, ",") {
+		t, ok := all[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown task %q", name)
+		}
+		selected = append(selected, t)
+	}
+	return selected, nil
+}
+
+// printTable shows a row per task and operators, with the baseline's success
+// rate if there is one
+func printTable(res net.BenchmarkResult, base *net.BenchmarkResult) {
+	baseRates := map[string]float64{}
+	if base != nil {
+		for _, t := range base.Tasks {
+			baseRates[t.Key()] = t.SuccessRate
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "task\toperators\tsolved\tgenerations\tfitness\tneurons\tsynapses\tbaseline")
+	for _, t := range res.Tasks {
+		b := "-"
+		if rate, ok := baseRates[t.Key()]; ok {
+			b = fmt.Sprintf("%.0f%%", rate*100)
+		}
+		fmt.Fprintf(w, "%s\t%s/%s\t%.0f%%\t%.1f\t%.4f\t%.1f\t%.1f\t%s\n",
+			t.Task, t.Mutation, t.Crossover, t.SuccessRate*100, t.Generations, t.Fitness, t.Neurons, t.Synapses, b)
+	}
+	w.Flush()
+}
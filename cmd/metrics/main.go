@@ -0,0 +1,227 @@
+// Command metrics summarises or plots a metrics log written by the -metrics
+// flag of snake or predictor.
+//
+//	metrics summary log.jsonl
+//	metrics plot -field mean -width 80 -height 20 log.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Wouterbeets/net"
+)
+
+const usage = "usage: metrics summary file | metrics plot [-field best] [-width 72] [-height 20] file"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "summary":
+		err = summaryCmd(os.Args[2:])
+	case "plot":
+		err = plotCmd(os.Args[2:])
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func load(path string) ([]net.GenerationMetrics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	metrics, err := net.ReadMetrics(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("%s: no generations", path)
+	}
+	return metrics, nil
+}
+
+func summaryCmd(args []string) error {
+	fs := flag.NewFlagSet("summary", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf(usage)
+	}
+	metrics, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	first, last := metrics[0], metrics[len(metrics)-1]
+
+	var evalSeconds float64
+	var mutations net.MutationStats
+	bestGen := first
+	for _, m := range metrics {
+		evalSeconds += m.EvalSeconds
+		mutations.Mutations += m.Mutations.Mutations
+		mutations.NeuronsAdded += m.Mutations.NeuronsAdded
+		mutations.SynapsesAdded += m.Mutations.SynapsesAdded
+		mutations.SynapsesRemoved += m.Mutations.SynapsesRemoved
+		if m.Best() < bestGen.Best() {
+			bestGen = m
+		}
+	}
+
+	fmt.Printf("generations: %d to %d (%d recorded)\n", first.Generation, last.Generation, len(metrics))
+	fmt.Printf("duration:    %s\n", last.Time.Sub(first.Time).Round(1e6))
+	fmt.Printf("eval time:   %.2fs, %.3fs per generation\n", evalSeconds, evalSeconds/float64(len(metrics)))
+	fmt.Printf("best:        %.6g at generation %d, first %.6g, last %.6g\n", bestGen.Best(), bestGen.Generation, first.Best(), last.Best())
+	fmt.Printf("mutations:   %d, %d neurons added, %d synapses added, %d removed\n",
+		mutations.Mutations, mutations.NeuronsAdded, mutations.SynapsesAdded, mutations.SynapsesRemoved)
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "population\tsize\tfinite\tbest\tmean\tstd\tspecies\tneurons\tsynapses\n")
+	for _, p := range last.Populations {
+		id := p.ID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.6g\t%.6g\t%.6g\t%d\t%.1f\t%.1f\n", id, p.Size, p.Finite, p.Best, p.Mean, p.Std, p.Species, p.Neurons, p.Synapses)
+	}
+	return w.Flush()
+}
+
+// fields aggregates a generation over its populations
+var fields = map[string]func(net.GenerationMetrics) float64{
+	"best": net.GenerationMetrics.Best,
+	"mean": func(m net.GenerationMetrics) float64 {
+		return average(m, func(p net.PopulationMetrics) float64 { return p.Mean })
+	},
+	"std": func(m net.GenerationMetrics) float64 {
+		return average(m, func(p net.PopulationMetrics) float64 { return p.Std })
+	},
+	"species": func(m net.GenerationMetrics) float64 {
+		var sum float64
+		for _, p := range m.Populations {
+			sum += float64(p.Species)
+		}
+		return sum
+	},
+	"neurons": func(m net.GenerationMetrics) float64 {
+		return average(m, func(p net.PopulationMetrics) float64 { return p.Neurons })
+	},
+	"synapses": func(m net.GenerationMetrics) float64 {
+		return average(m, func(p net.PopulationMetrics) float64 { return p.Synapses })
+	},
+	"eval":      func(m net.GenerationMetrics) float64 { return m.EvalSeconds },
+	"mutations": func(m net.GenerationMetrics) float64 { return float64(m.Mutations.Mutations) },
+}
+
+func average(m net.GenerationMetrics, f func(net.PopulationMetrics) float64) float64 {
+	if len(m.Populations) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, p := range m.Populations {
+		sum += f(p)
+	}
+	return sum / float64(len(m.Populations))
+}
+
+func plotCmd(args []string) error {
+	fs := flag.NewFlagSet("plot", flag.ExitOnError)
+	field := fs.String("field", "best", "best, mean, std, species, neurons, synapses, eval or mutations")
+	width := fs.Int("width", 72, "plot width in columns")
+	height := fs.Int("height", 20, "plot height in rows")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf(usage)
+	}
+	f, ok := fields[*field]
+	if !ok {
+		return fmt.Errorf("unknown field %q", *field)
+	}
+	if *width < 2 || *height < 2 {
+		return fmt.Errorf("width and height should be > 1")
+	}
+	metrics, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	values := make([]float64, len(metrics))
+	for i, m := range metrics {
+		values[i] = f(m)
+	}
+	fmt.Printf("%s per generation\n", *field)
+	fmt.Print(plot(values, *width, *height))
+	w := *width
+	if len(values) < w {
+		w = len(values)
+	}
+	lastGen := fmt.Sprint(metrics[len(metrics)-1].Generation)
+	fmt.Printf("%12s  %-*d%s\n", "", max(w-len(lastGen), 1), metrics[0].Generation, lastGen)
+	return nil
+}
+
+// plot draws values as columns of width, averaging the values that fall in
+// the same column
+func plot(values []float64, width, height int) string {
+	if len(values) < width {
+		width = len(values)
+	}
+	cols := make([]float64, width)
+	counts := make([]int, width)
+	for i, v := range values {
+		c := i * width / len(values)
+		cols[c] += v
+		counts[c]++
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for c := range cols {
+		cols[c] /= float64(counts[c])
+		lo = math.Min(lo, cols[c])
+		hi = math.Max(hi, cols[c])
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	grid := make([][]rune, height)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", width))
+	}
+	for c, v := range cols {
+		r := int(math.Round((hi - v) / (hi - lo) * float64(height-1)))
+		grid[r][c] = '*'
+	}
+	var b strings.Builder
+	for r, row := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = fmt.Sprintf("%.4g", hi)
+		case height - 1:
+			label = fmt.Sprintf("%.4g", lo)
+		}
+		fmt.Fprintf(&b, "%12s |%s\n", label, string(row))
+	}
+	fmt.Fprintf(&b, "%12s +%s\n", "", strings.Repeat("-", width))
+	return b.String()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Wouterbeets/net"
+)
+
+// loadNet loads the genome of the only argument of fs
+func loadNet(fs *flag.FlagSet) (net.DNA, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return net.DNA{}, fmt.Errorf("expected one file")
+	}
+	return net.LoadDNA(fs.Arg(0))
+}
+
+func dotCmd(args []string) error {
+	fs := flag.NewFlagSet("dot", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), "usage: netctl dot file") }
+	fs.Parse(args)
+	dna, err := loadNet(fs)
+	if err != nil {
+		return err
+	}
+	n, err := net.DNAToNet(dna)
+	if err != nil {
+		return err
+	}
+	net.ToDot(n)
+	return nil
+}
+
+func svgCmd(args []string) error {
+	fs := flag.NewFlagSet("svg", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), "usage: netctl svg file > net.svg") }
+	fs.Parse(args)
+	dna, err := loadNet(fs)
+	if err != nil {
+		return err
+	}
+	return net.WriteSVG(os.Stdout, dna)
+}
+
+func convertCmd(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netctl convert in out")
+		fmt.Fprintln(fs.Output(), "out is written in binary if it ends in .bin and as json otherwise, in can be either")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two files")
+	}
+	dna, err := net.LoadDNA(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return net.SaveDNA(fs.Arg(1), dna)
+}
+
+func validateCmd(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), "usage: netctl validate file...") }
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files given")
+	}
+	invalid := 0
+	for _, path := range fs.Args() {
+		dna, err := net.LoadDNA(path)
+		if err == nil {
+			err = dna.Validate()
+		}
+		if err != nil {
+			invalid++
+			fmt.Printf("%s: %s\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: ok\n", path)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d invalid", invalid, fs.NArg())
+	}
+	return nil
+}
+
+func pruneCmd(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 0, "also remove synapses with an absolute weight of at most this, above 0 changes the output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netctl prune [flags] in out")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two files")
+	}
+	dna, err := net.LoadDNA(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pruned := dna.Prune(*threshold)
+	fmt.Printf("neurons %d -> %d, synapses %d -> %d\n",
+		len(dna.Neurons), len(pruned.Neurons), len(dna.SynapseMap), len(pruned.SynapseMap))
+	return net.SaveDNA(fs.Arg(1), pruned)
+}
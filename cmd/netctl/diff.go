@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Wouterbeets/net"
+)
+
+func diffCmd(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	dot := fs.Bool("dot", false, "print a colour coded graphviz graph instead of text")
+	exitCode := fs.Bool("exit-code", false, "exit with status 1 if the genomes differ")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netctl diff [flags] before after")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two files")
+	}
+	before, err := net.LoadDNA(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := net.LoadDNA(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d := net.Diff(before, after)
+	if *dot {
+		err = d.WriteDot(os.Stdout)
+	} else {
+		err = d.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if *exitCode && !d.Empty() {
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Wouterbeets/net"
+)
+
+func evalCmd(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	ticks := fs.Int("ticks", 0, "evaluate in step mode with this many ticks per input")
+	reset := fs.Bool("reset", false, "reset the net before every line of stdin instead of keeping its state")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netctl eval [flags] file [input...]")
+		fmt.Fprintln(fs.Output(), "without inputs every line of stdin is an input, numbers separated by spaces or commas")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no file given")
+	}
+	dna, err := net.LoadDNA(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	n, err := net.DNAToNet(dna)
+	if err != nil {
+		return err
+	}
+	n.StepMode(*ticks)
+
+	if fs.NArg() > 1 {
+		in, err := parseInput(fs.Args()[1:])
+		if err != nil {
+			return err
+		}
+		return evalPrint(n, in)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		in, err := parseInput(fields)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if *reset {
+			n.Reset()
+		}
+		if err := evalPrint(n, in); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseInput(fields []string) ([]float64, error) {
+	in := make([]float64, len(fields))
+	for i, f := range fields {
+		var err error
+		if in[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+func evalPrint(n *net.Net, in []float64) error {
+	if len(in) != n.InSize() {
+		return fmt.Errorf("%d inputs, the net has %d", len(in), n.InSize())
+	}
+	out, err := n.Eval(in)
+	if err != nil {
+		return err
+	}
+	s := make([]string, len(out))
+	for i, v := range out {
+		s[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	fmt.Println(strings.Join(s, " "))
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/Wouterbeets/net"
+)
+
+func infoCmd(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netctl info file...")
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files given")
+	}
+	for i, path := range fs.Args() {
+		dna, err := net.LoadDNA(path)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(path)
+		printInfo(dna)
+	}
+	return nil
+}
+
+// layer names, by NeuronGene.Layer
+var layerNames = []string{"input", "hidden", "output"}
+
+func printInfo(dna net.DNA) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	layers := make(map[int]int)
+	kinds := make(map[string]int)
+	for _, ng := range dna.Neurons {
+		layers[ng.Layer]++
+		kinds[net.KindName(ng.Kind)]++
+	}
+	for l, name := range layerNames {
+		fmt.Fprintf(w, "  %s neurons\t%d\n", name, layers[l])
+	}
+	fmt.Fprintf(w, "  kinds\t%s\n", counts(kinds))
+	fmt.Fprintf(w, "  synapses\t%d\n", len(dna.SynapseMap))
+
+	self, back := cycles(dna)
+	fmt.Fprintf(w, "  self loops\t%d\n", self)
+	fmt.Fprintf(w, "  recurrent synapses\t%d\n", back)
+	unused := len(dna.Neurons) - len(dna.Prune(-1).Neurons)
+	fmt.Fprintf(w, "  hidden neurons not leading to an output\t%d\n", unused)
+
+	if len(dna.SynapseMap) > 0 {
+		lo, hi, abs := math.Inf(1), math.Inf(-1), 0.0
+		for syn := range dna.SynapseMap {
+			lo = math.Min(lo, syn.Weight)
+			hi = math.Max(hi, syn.Weight)
+			abs += math.Abs(syn.Weight)
+		}
+		fmt.Fprintf(w, "  weights\t%.4g to %.4g, mean |w| %.4g\n", lo, hi, abs/float64(len(dna.SynapseMap)))
+	}
+	if err := dna.Validate(); err != nil {
+		fmt.Fprintf(w, "  problems\t%s\n", err)
+	}
+}
+
+// counts formats a count per name, sorted by name
+func counts(m map[string]int) string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s := ""
+	for i, name := range names {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf("%d %s", m[name], name)
+	}
+	return s
+}
+
+// cycles counts the synapses from a neuron to itself and the other synapses
+// that close a cycle, found walking depth first from the inputs and then
+// from the remaining neurons, both in id order
+func cycles(dna net.DNA) (self, back int) {
+	next := make(map[int][]int)
+	ids := make(map[int]bool)
+	for syn := range dna.SynapseMap {
+		if syn.SourceID == syn.DestID {
+			self++
+			continue
+		}
+		next[syn.SourceID] = append(next[syn.SourceID], syn.DestID)
+		ids[syn.SourceID], ids[syn.DestID] = true, true
+	}
+	for _, ng := range dna.Neurons {
+		ids[ng.ID] = true
+	}
+	for id := range next {
+		sort.Ints(next[id])
+	}
+	order := make([]int, 0, len(ids))
+	for id := range ids {
+		order = append(order, id)
+	}
+	layer := make(map[int]int)
+	for _, ng := range dna.Neurons {
+		layer[ng.ID] = ng.Layer
+	}
+	sort.Slice(order, func(i, j int) bool {
+		// inputs first, 0 is the input layer
+		if (layer[order[i]] == 0) != (layer[order[j]] == 0) {
+			return layer[order[i]] == 0
+		}
+		return order[i] < order[j]
+	})
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int]int)
+	var visit func(id int)
+	visit = func(id int) {
+		state[id] = visiting
+		for _, dest := range next[id] {
+			switch state[dest] {
+			case visiting:
+				back++
+			case unvisited:
+				visit(dest)
+			}
+		}
+		state[id] = done
+	}
+	for _, id := range order {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return self, back
+}
@@ -0,0 +1,57 @@
+// Command netctl inspects and converts genomes, DNA files in json or binary.
+//
+//	netctl info net.json               sizes, kinds, cycles and weights
+//	netctl eval net.json 1 0           evaluate an input, or lines of stdin
+//	netctl dot net.json                graphviz dot
+//	netctl svg net.json > net.svg      svg drawing
+//	netctl convert net.json net.bin    convert between json and binary
+//	netctl validate net.json...        check genomes for problems
+//	netctl diff [-dot] a.json b.json    compare two genomes
+//	netctl prune net.json small.json   remove what doesn't affect the output
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var commands = map[string]func(args []string) error{
+	"info":     infoCmd,
+	"eval":     evalCmd,
+	"dot":      dotCmd,
+	"svg":      svgCmd,
+	"convert":  convertCmd,
+	"validate": validateCmd,
+	"diff":     diffCmd,
+	"prune":    pruneCmd,
+}
+
+const usage = `usage: netctl <command> [flags] file...
+
+commands:
+  info      sizes, kinds, cycles and weights of a genome
+  eval      evaluate inputs given as arguments or as lines on stdin
+  dot       print the net in graphviz dot
+  svg       print the net as svg
+  convert   convert a genome between json and binary (.bin)
+  validate  check genomes for problems
+  diff      compare two genomes
+  prune     remove neurons and synapses that don't affect the output
+
+run netctl <command> -h for the flags of a command`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "netctl "+os.Args[1]+":", err)
+		os.Exit(1)
+	}
+}
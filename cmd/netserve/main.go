@@ -0,0 +1,42 @@
+// Command netserve serves a saved net over http.
+//
+//	POST /eval             {"input": [...]} or {"inputs": [[...], ...]}, with an
+//	                       optional "session" to keep recurrent state
+//	POST /sessions         starts a session, returns {"session": id}
+//	DELETE /sessions/{id}  ends a session
+//	GET /info              sizes and topology of the model
+//	GET /metrics           prometheus metrics
+//
+// The model file is a DNA file in json, as written by snake -headless, or in
+// the binary format of netctl convert. It's reloaded when it changes, running
+// sessions keep the net they started with.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	modelPath  = flag.String("model", "best.json", "DNA file of the net to serve, json or binary")
+	addr       = flag.String("addr", ":8080", "address to listen on")
+	reload     = flag.Duration("reload", 2*time.Second, "how often to check the model file for changes, 0 disables reloading")
+	sessionTTL = flag.Duration("session-ttl", 10*time.Minute, "sessions unused for this long are ended")
+	ticks      = flag.Int("ticks", 0, "evaluate in step mode with this many ticks per input, 0 uses the default evaluation")
+)
+
+func main() {
+	flag.Parse()
+	s, err := newServer(*modelPath, *ticks, *sessionTTL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *reload > 0 {
+		go s.watch(*reload)
+	}
+	go s.expireSessions(*sessionTTL / 2)
+	log.Printf("serving %s (%s) on %s", *modelPath, s.current().version, *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
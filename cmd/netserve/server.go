@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wouterbeets/net"
+)
+
+// model is a loaded model file, it isn't changed after loading
+type model struct {
+	dna     net.DNA
+	version string
+	loaded  time.Time
+	modTime time.Time
+	size    int64
+	in, out int
+}
+
+// changed returns whether the file in info isn't the loaded one
+func (m *model) changed(info os.FileInfo) bool {
+	return !info.ModTime().Equal(m.modTime) || info.Size() != m.size
+}
+
+// session is a net that keeps its recurrent state between requests
+type session struct {
+	mu    sync.Mutex
+	net   *net.Net
+	model *model
+	used  time.Time
+}
+
+type server struct {
+	path  string
+	ticks int
+	ttl   time.Duration
+	mux   *http.ServeMux
+	stats *stats
+
+	mu       sync.RWMutex
+	model    *model
+	sessions map[string]*session
+}
+
+func newServer(path string, ticks int, ttl time.Duration) (*server, error) {
+	m, err := loadModel(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{
+		path:     path,
+		ticks:    ticks,
+		ttl:      ttl,
+		mux:      http.NewServeMux(),
+		stats:    newStats(),
+		model:    m,
+		sessions: make(map[string]*session),
+	}
+	s.mux.HandleFunc("/eval", s.handleEval)
+	s.mux.HandleFunc("/sessions", s.handleSessions)
+	s.mux.HandleFunc("/sessions/", s.handleSession)
+	s.mux.HandleFunc("/info", s.handleInfo)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	return s, nil
+}
+
+func loadModel(path string) (*model, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dna, err := net.DecodeDNA(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read model %s: %w", path, err)
+	}
+	n, err := net.DNAToNet(dna)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model %s: %w", path, err)
+	}
+	sum := sha256.Sum256(b)
+	return &model{
+		// neurons only a synapse refers to get a random bias in DNAToNet,
+		// going through the net once fixes it for every net made from dna
+		dna:     net.NetToDna(n),
+		version: hex.EncodeToString(sum[:6]),
+		loaded:  time.Now(),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		in:      n.InSize(),
+		out:     n.OutSize(),
+	}, nil
+}
+
+func (s *server) current() *model {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.model
+}
+
+// newNet builds a fresh net of m
+func (s *server) newNet(m *model) (*net.Net, error) {
+	n, err := net.DNAToNet(m.dna)
+	if err != nil {
+		return nil, err
+	}
+	n.StepMode(s.ticks)
+	return n, nil
+}
+
+// watch reloads the model when its file changes, a model that fails to load
+// is logged and the old one is kept
+func (s *server) watch(every time.Duration) {
+	var broken os.FileInfo
+	for range time.Tick(every) {
+		broken = s.reload(broken)
+	}
+}
+
+// reload loads the model file if it changed and isn't the broken file that
+// failed to load before, it returns the file if it's broken
+func (s *server) reload(broken os.FileInfo) os.FileInfo {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return broken
+	}
+	old := s.current()
+	if !old.changed(info) {
+		return broken
+	}
+	if broken != nil && info.ModTime().Equal(broken.ModTime()) && info.Size() == broken.Size() {
+		// don't try the same broken file again
+		return broken
+	}
+	m, err := loadModel(s.path)
+	if err != nil {
+		s.stats.reloadErrors.add(1)
+		log.Printf("keeping model %s: %s", old.version, err)
+		return info
+	}
+	s.mu.Lock()
+	s.model = m
+	s.mu.Unlock()
+	s.stats.reloads.add(1)
+	log.Printf("reloaded %s: %s -> %s", s.path, old.version, m.version)
+	return nil
+}
+
+// expireSessions ends sessions that weren't used for the ttl
+func (s *server) expireSessions(every time.Duration) {
+	if every <= 0 {
+		return
+	}
+	for now := range time.Tick(every) {
+		s.mu.Lock()
+		for id, sess := range s.sessions {
+			sess.mu.Lock()
+			if now.Sub(sess.used) > s.ttl {
+				delete(s.sessions, id)
+			}
+			sess.mu.Unlock()
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+	s.mux.ServeHTTP(rec, r)
+	s.stats.request(routeLabel(r.URL.Path), rec.status, time.Since(start))
+}
+
+// routes are the paths the metrics are labelled with, anything else is
+// "other" so unknown paths don't add metrics
+var routes = map[string]bool{"/eval": true, "/sessions": true, "/info": true, "/metrics": true}
+
+func routeLabel(path string) string {
+	switch {
+	case routes[path]:
+		return path
+	case strings.HasPrefix(path, "/sessions/"):
+		return "/sessions/{id}"
+	}
+	return "other"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+type evalRequest struct {
+	Input   []float64   `json:"input,omitempty"`
+	Inputs  [][]float64 `json:"inputs,omitempty"`
+	Session string      `json:"session,omitempty"`
+}
+
+type evalResponse struct {
+	Output  []float64   `json:"output,omitempty"`
+	Outputs [][]float64 `json:"outputs,omitempty"`
+	Session string      `json:"session,omitempty"`
+	Version string      `json:"version"`
+}
+
+// handleEval evaluates one input or a batch. Without a session every input
+// gets a fresh net, with one the inputs are a sequence fed to the session's
+// net in order.
+func (s *server) handleEval(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	var req evalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid json: %s", err)
+		return
+	}
+	batch := req.Inputs != nil
+	inputs := req.Inputs
+	if !batch {
+		if req.Input == nil {
+			httpError(w, http.StatusBadRequest, "set input or inputs")
+			return
+		}
+		inputs = [][]float64{req.Input}
+	}
+
+	var outputs [][]float64
+	var m *model
+	var err error
+	start := time.Now()
+	if req.Session != "" {
+		outputs, m, err = s.evalSession(req.Session, inputs)
+	} else {
+		m = s.current()
+		outputs, err = s.eval(m, inputs)
+	}
+	s.stats.eval(len(inputs), time.Since(start))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if _, ok := err.(inputError); ok {
+			status = http.StatusBadRequest
+		} else if err == errUnknownSession {
+			status = http.StatusNotFound
+		}
+		httpError(w, status, "%s", err)
+		return
+	}
+
+	res := evalResponse{Session: req.Session, Version: m.version}
+	if batch {
+		res.Outputs = outputs
+	} else {
+		res.Output = outputs[0]
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+var errUnknownSession = fmt.Errorf("unknown session")
+
+// inputError is a request the model can't evaluate, other eval errors are the
+// server's
+type inputError string
+
+func (e inputError) Error() string { return string(e) }
+
+func checkInput(m *model, i int, in []float64) error {
+	if len(in) != m.in {
+		return inputError(fmt.Sprintf("input %d has size %d, expected %d", i, len(in), m.in))
+	}
+	return nil
+}
+
+func (s *server) eval(m *model, inputs [][]float64) ([][]float64, error) {
+	outputs := make([][]float64, len(inputs))
+	for i, in := range inputs {
+		if err := checkInput(m, i, in); err != nil {
+			return nil, err
+		}
+		n, err := s.newNet(m)
+		if err != nil {
+			return nil, err
+		}
+		if outputs[i], err = n.Eval(in); err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+func (s *server) evalSession(id string, inputs [][]float64) ([][]float64, *model, error) {
+	s.mu.RLock()
+	sess, ok := s.sessions[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, errUnknownSession
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.used = time.Now()
+	for i, in := range inputs {
+		if err := checkInput(sess.model, i, in); err != nil {
+			return nil, nil, err
+		}
+	}
+	outputs := make([][]float64, len(inputs))
+	for i, in := range inputs {
+		var err error
+		if outputs[i], err = sess.net.Eval(in); err != nil {
+			return nil, nil, err
+		}
+	}
+	return outputs, sess.model, nil
+}
+
+// handleSessions starts a session on the current model
+func (s *server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	m := s.current()
+	n, err := s.newNet(m)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "%s", err)
+		return
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		httpError(w, http.StatusInternalServerError, "%s", err)
+		return
+	}
+	id := hex.EncodeToString(b)
+	s.mu.Lock()
+	s.sessions[id] = &session{net: n, model: m, used: time.Now()}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, map[string]string{"session": id, "version": m.version})
+}
+
+// handleSession ends a session
+func (s *server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httpError(w, http.StatusMethodNotAllowed, "use DELETE")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/sessions/")
+	s.mu.Lock()
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if !ok {
+		httpError(w, http.StatusNotFound, "%s", errUnknownSession)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type info struct {
+	Model    string         `json:"model"`
+	Version  string         `json:"version"`
+	Loaded   time.Time      `json:"loaded"`
+	Inputs   int            `json:"inputs"`
+	Outputs  int            `json:"outputs"`
+	Hidden   int            `json:"hidden"`
+	Synapses int            `json:"synapses"`
+	Kinds    map[string]int `json:"kinds"`
+	Ticks    int            `json:"ticks"`
+	Sessions int            `json:"sessions"`
+}
+
+func (s *server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	m := s.current()
+	s.mu.RLock()
+	sessions := len(s.sessions)
+	s.mu.RUnlock()
+	res := info{
+		Model:    s.path,
+		Version:  m.version,
+		Loaded:   m.loaded,
+		Inputs:   m.in,
+		Outputs:  m.out,
+		Hidden:   len(m.dna.Neurons) - m.in - m.out,
+		Synapses: len(m.dna.SynapseMap),
+		Kinds:    make(map[string]int),
+		Ticks:    s.ticks,
+		Sessions: sessions,
+	}
+	for _, ng := range m.dna.Neurons {
+		res.Kinds[net.KindName(ng.Kind)]++
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	sessions := len(s.sessions)
+	version := s.model.version
+	s.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.stats.write(w, sessions, version)
+}
+
+// writeJSON writes v with status, or a 500 if v can't be encoded, like an
+// output that isn't finite
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		httpError(w, http.StatusInternalServerError, "unable to encode response: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("unable to write response: %s", err)
+	}
+}
+
+func httpError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	// a map of strings always encodes
+	b, _ := json.Marshal(map[string]string{"error": fmt.Sprintf(format, args...)})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		log.Printf("unable to write response: %s", err)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wouterbeets/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModel writes a random net with in inputs to path
+func writeModel(t *testing.T, path string, in int) {
+	rng := rand.New(rand.NewSource(int64(in)))
+	r := func() float64 { return rng.Float64()*2 - 1 }
+	n, err := net.NewBuilder().Size(in, 3, 2).WeightFunc(r).BiasFunc(r).Build()
+	require.NoError(t, err)
+	b, err := json.Marshal(net.NetToDna(n))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, b, 0644))
+}
+
+func newTestServer(t *testing.T) (*server, string) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	writeModel(t, path, 2)
+	// a tick per input, so the input reaches the outputs over several calls
+	s, err := newServer(path, 1, time.Minute)
+	require.NoError(t, err)
+	return s, path
+}
+
+// do sends body to the server and decodes the json response into res
+func do(t *testing.T, s *server, method, path string, body, res interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	if res != nil {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res), w.Body.String())
+	}
+	return w.Code
+}
+
+func startSession(t *testing.T, s *server) string {
+	var res map[string]string
+	require.Equal(t, http.StatusCreated, do(t, s, http.MethodPost, "/sessions", nil, &res))
+	require.NotEmpty(t, res["session"])
+	return res["session"]
+}
+
+func TestEval(t *testing.T) {
+	s, _ := newTestServer(t)
+	ref, err := s.newNet(s.current())
+	require.NoError(t, err)
+	want, err := ref.Eval([]float64{1, 0})
+	require.NoError(t, err)
+
+	var res evalResponse
+	assert.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1, 0}}, &res))
+	assert.Equal(t, want, res.Output)
+	assert.Equal(t, s.current().version, res.Version)
+
+	// every input of a batch gets a fresh net
+	res = evalResponse{}
+	assert.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Inputs: [][]float64{{1, 0}, {1, 0}}}, &res))
+	assert.Equal(t, [][]float64{want, want}, res.Outputs)
+
+	var e map[string]string
+	assert.Equal(t, http.StatusBadRequest, do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1}}, &e))
+	assert.Contains(t, e["error"], "size 1")
+	assert.Equal(t, http.StatusBadRequest, do(t, s, http.MethodPost, "/eval", evalRequest{}, &e))
+	assert.Equal(t, http.StatusMethodNotAllowed, do(t, s, http.MethodGet, "/eval", nil, &e))
+}
+
+func TestSession(t *testing.T) {
+	s, _ := newTestServer(t)
+	id := startSession(t, s)
+
+	ref, err := s.newNet(s.current())
+	require.NoError(t, err)
+	inputs := [][]float64{{1, 0}, {0, 1}, {1, 1}}
+	var fresh evalResponse
+	require.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Input: inputs[1]}, &fresh))
+	for i, in := range inputs {
+		want, err := ref.Eval(in)
+		require.NoError(t, err)
+		var res evalResponse
+		require.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Input: in, Session: id}, &res))
+		assert.Equal(t, want, res.Output)
+		assert.Equal(t, id, res.Session)
+		if i == 1 {
+			// the first input is still in the net
+			assert.NotEqual(t, fresh.Output, res.Output)
+		}
+	}
+
+	// a batch continues the sequence
+	var want [][]float64
+	for _, in := range inputs {
+		out, err := ref.Eval(in)
+		require.NoError(t, err)
+		want = append(want, out)
+	}
+	var res evalResponse
+	require.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Inputs: inputs, Session: id}, &res))
+	assert.Equal(t, want, res.Outputs)
+
+	assert.Equal(t, http.StatusNoContent, do(t, s, http.MethodDelete, "/sessions/"+id, nil, nil))
+	var e map[string]string
+	assert.Equal(t, http.StatusNotFound, do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1, 0}, Session: id}, &e))
+	assert.Equal(t, errUnknownSession.Error(), e["error"])
+	assert.Equal(t, http.StatusNotFound, do(t, s, http.MethodDelete, "/sessions/"+id, nil, &e))
+}
+
+func TestReload(t *testing.T) {
+	s, path := newTestServer(t)
+	old := s.current().version
+	id := startSession(t, s)
+
+	writeModel(t, path, 3)
+	assert.Nil(t, s.reload(nil))
+	assert.NotEqual(t, old, s.current().version)
+
+	// the session keeps the model it started with
+	var res evalResponse
+	assert.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1, 0}, Session: id}, &res))
+	assert.Equal(t, old, res.Version)
+
+	// new requests get the new model
+	res = evalResponse{}
+	assert.Equal(t, http.StatusOK, do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1, 0, 1}}, &res))
+	assert.Equal(t, s.current().version, res.Version)
+
+	// a broken file keeps the model and isn't tried again
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	broken := s.reload(nil)
+	assert.NotNil(t, broken)
+	assert.Equal(t, res.Version, s.current().version)
+	assert.Equal(t, broken, s.reload(broken))
+	assert.Equal(t, int64(1), s.stats.reloadErrors.get())
+}
+
+func TestMetrics(t *testing.T) {
+	s, _ := newTestServer(t)
+	do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1, 0}}, nil)
+	do(t, s, http.MethodPost, "/eval", evalRequest{Input: []float64{1}}, nil)
+	do(t, s, http.MethodDelete, "/sessions/nope", nil, nil)
+	do(t, s, http.MethodGet, "/nope\"", nil, nil)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	for _, line := range []string{
+		`netserve_requests_total{path="/eval",code="200"} 1`,
+		`netserve_requests_total{path="/eval",code="400"} 1`,
+		`netserve_requests_total{path="/sessions/{id}",code="404"} 1`,
+		`netserve_requests_total{path="other",code="404"} 1`,
+		`netserve_request_duration_seconds_count{path="/eval"} 2`,
+		`netserve_inputs_total 2`,
+		`netserve_sessions 0`,
+		`netserve_model_info{version="` + s.current().version + `"} 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "nope")
+
+	assert.Equal(t, `path="a\"b\\c\nd"`, label("path", "a\"b\\c\nd"))
+}
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, evalResponse{Output: []float64{math.NaN()}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), `{"error":`))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// counter is a prometheus counter
+type counter struct{ v int64 }
+
+func (c *counter) add(n int64) { atomic.AddInt64(&c.v, n) }
+func (c *counter) get() int64  { return atomic.LoadInt64(&c.v) }
+
+// durationBuckets are the upper bounds in seconds of the latency histograms
+var durationBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// histogram is a prometheus histogram of durations
+type histogram struct {
+	counts []int64 // per bucket, not cumulative
+	count  int64
+	sum    float64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]int64, len(durationBuckets))
+	}
+	s := d.Seconds()
+	for i, le := range durationBuckets {
+		if s <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += s
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var cum int64
+	for i, le := range durationBuckets {
+		if h.counts != nil {
+			cum += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%g\"} %d\n", name, labels, sep, le, cum)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// labelEscaper escapes a prometheus label value
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label formats name="value"
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
+
+type requestKey struct {
+	path string
+	code int
+}
+
+// stats collects the metrics served on /metrics
+type stats struct {
+	reloads      counter
+	reloadErrors counter
+	inputs       counter
+
+	mu       sync.Mutex
+	requests map[requestKey]int64
+	latency  map[string]*histogram
+	evals    histogram
+}
+
+func newStats() *stats {
+	return &stats{
+		requests: make(map[requestKey]int64),
+		latency:  make(map[string]*histogram),
+	}
+}
+
+func (s *stats) request(path string, code int, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[requestKey{path, code}]++
+	h, ok := s.latency[path]
+	if !ok {
+		h = &histogram{}
+		s.latency[path] = h
+	}
+	h.observe(d)
+}
+
+func (s *stats) eval(inputs int, d time.Duration) {
+	s.inputs.add(int64(inputs))
+	s.mu.Lock()
+	s.evals.observe(d)
+	s.mu.Unlock()
+}
+
+func (s *stats) write(w io.Writer, sessions int, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP netserve_requests_total HTTP requests by path and status code.")
+	fmt.Fprintln(w, "# TYPE netserve_requests_total counter")
+	keys := make([]requestKey, 0, len(s.requests))
+	for k := range s.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path == keys[j].path {
+			return keys[i].code < keys[j].code
+		}
+		return keys[i].path < keys[j].path
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "netserve_requests_total{%s,code=\"%d\"} %d\n", label("path", k.path), k.code, s.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP netserve_request_duration_seconds HTTP request latency by path.")
+	fmt.Fprintln(w, "# TYPE netserve_request_duration_seconds histogram")
+	paths := make([]string, 0, len(s.latency))
+	for p := range s.latency {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		s.latency[p].write(w, "netserve_request_duration_seconds", label("path", p))
+	}
+
+	fmt.Fprintln(w, "# HELP netserve_eval_duration_seconds Time spent evaluating the inputs of an eval request.")
+	fmt.Fprintln(w, "# TYPE netserve_eval_duration_seconds histogram")
+	s.evals.write(w, "netserve_eval_duration_seconds", "")
+
+	fmt.Fprintln(w, "# HELP netserve_inputs_total Inputs evaluated.")
+	fmt.Fprintln(w, "# TYPE netserve_inputs_total counter")
+	fmt.Fprintf(w, "netserve_inputs_total %d\n", s.inputs.get())
+
+	fmt.Fprintln(w, "# HELP netserve_sessions Open sessions.")
+	fmt.Fprintln(w, "# TYPE netserve_sessions gauge")
+	fmt.Fprintf(w, "netserve_sessions %d\n", sessions)
+
+	fmt.Fprintln(w, "# HELP netserve_model_reloads_total Successful reloads of the model file.")
+	fmt.Fprintln(w, "# TYPE netserve_model_reloads_total counter")
+	fmt.Fprintf(w, "netserve_model_reloads_total %d\n", s.reloads.get())
+
+	fmt.Fprintln(w, "# HELP netserve_model_reload_errors_total Reloads of the model file that failed.")
+	fmt.Fprintln(w, "# TYPE netserve_model_reload_errors_total counter")
+	fmt.Fprintf(w, "netserve_model_reload_errors_total %d\n", s.reloadErrors.get())
+
+	fmt.Fprintln(w, "# HELP netserve_model_info The version of the loaded model.")
+	fmt.Fprintln(w, "# TYPE netserve_model_info gauge")
+	fmt.Fprintf(w, "netserve_model_info{%s} 1\n", label("version", version))
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
 
 	"github.com/MaxHalford/eaopt"
 	"github.com/Wouterbeets/net"
@@ -48,6 +50,12 @@ func scoreXOR(scores [][]float64) (score float64) {
 }
 
 func (p *Predictor) Evaluate() (float64, error) {
+	score, err := p.score()
+	return score + p.Size(), err
+}
+
+// score is the XOR score without the size penalty
+func (p *Predictor) score() (float64, error) {
 	var scores [][]float64
 	out, err := p.Net.Eval([]float64{0, 0})
 	if err != nil {
@@ -85,12 +93,16 @@ func (p *Predictor) Evaluate() (float64, error) {
 	}
 	scores = append(scores, out)
 
-	return scoreXOR(scores) + p.Size(), nil
+	return scoreXOR(scores), nil
 }
 
 func (p *Predictor) Mutate(rng *rand.Rand) {
 
 	dna := net.NetToDna(p.Net)
+	var before net.DNA
+	if metrics != nil {
+		before = dna.Clone()
+	}
 	var weights []float64
 	var sources []int
 	var destinations []int
@@ -99,7 +111,6 @@ func (p *Predictor) Mutate(rng *rand.Rand) {
 		synapses = append(synapses, &net.SynapseGene{
 			SourceID: syn.SourceID,
 			DestID:   syn.DestID,
-			DestBias: syn.DestBias,
 			Weight:   syn.Weight,
 		})
 	}
@@ -155,6 +166,10 @@ func (p *Predictor) Mutate(rng *rand.Rand) {
 		}
 	}
 
+	if metrics != nil {
+		metrics.Mutations.Record(before, dna)
+	}
+
 	n, err := net.DNAToNet(dna)
 	if err != nil {
 		fmt.Println("unable to construct net, keeping old net")
@@ -177,7 +192,6 @@ func (p *Predictor) Crossover(genome eaopt.Genome, rng *rand.Rand) {
 		pSynapses = append(pSynapses, &net.SynapseGene{
 			SourceID: syn.SourceID,
 			DestID:   syn.DestID,
-			DestBias: syn.DestBias,
 			Weight:   syn.Weight,
 		})
 	}
@@ -186,7 +200,6 @@ func (p *Predictor) Crossover(genome eaopt.Genome, rng *rand.Rand) {
 		p2Synapses = append(p2Synapses, &net.SynapseGene{
 			SourceID: syn.SourceID,
 			DestID:   syn.DestID,
-			DestBias: syn.DestBias,
 			Weight:   syn.Weight,
 		})
 	}
@@ -232,7 +245,88 @@ func NewPredictor(rng *rand.Rand) eaopt.Genome {
 	return &Predictor{Net: n}
 }
 
+var metricsPath = flag.String("metrics", "", "file per generation metrics of the ga are written to, csv for a .csv file and json lines otherwise")
+
+// metrics is set when -metrics is given
+var metrics *net.MetricsRecorder
+
+var optimizer = flag.String("optimizer", "ga", "optimizer: ga evolves topology and weights, nsga2 does too with size as a separate objective, es and cmaes only the weights of a fixed topology")
+
+// runNSGA2 evolves the XOR score and the net's complexity as two objectives
+// and returns the Pareto front
+func runNSGA2() ([]net.MultiIndividual, error) {
+	ns := net.NewNSGA2(func(dna net.DNA) ([]float64, error) {
+		n, err := net.DNAToNet(dna)
+		if err != nil {
+			return nil, err
+		}
+		score, err := (&Predictor{Net: n}).score()
+		return []float64{score, net.Complexity(dna)}, err
+	})
+	ns.PopSize = 500
+	ns.Generations = 3500
+	ns.Callback = func(ns *net.NSGA2) {
+		fmt.Printf("Pareto front at generation %d: %d nets\n", ns.Generation, len(ns.Front()))
+	}
+	err := ns.Minimize(func(rng *rand.Rand) net.DNA {
+		return net.NetToDna(NewPredictor(rng).(*Predictor).Net)
+	})
+	return ns.Front(), err
+}
+
+// runES optimizes the weights of a fixed topology net with an evolution
+// strategy
+func runES(kind string) (*net.Net, error) {
+	n, err := net.NewBuilder().Size(2, 1, 2).Build()
+	if err != nil {
+		return nil, err
+	}
+	fitness := func(n *net.Net) (float64, error) {
+		return (&Predictor{Net: n}).Evaluate()
+	}
+	err = net.OptimizeParams(n, kind, 3500, fitness, func(gen int, best float64) {
+		fmt.Printf("Best fitness at generation %d: %.30f\n", gen, best)
+	})
+	return n, err
+}
+
 func main() {
+	flag.Parse()
+
+	if *optimizer == "nsga2" {
+		front, err := runNSGA2()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, indi := range front {
+			n, err := net.DNAToNet(indi.DNA)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("score: %f\tcomplexity: %.0f\n", indi.Objectives[0], indi.Objectives[1])
+			fmt.Println(n.Eval([]float64{0, 0}))
+			fmt.Println(n.Eval([]float64{0, 1}))
+			fmt.Println(n.Eval([]float64{1, 0}))
+			fmt.Println(n.Eval([]float64{1, 1}))
+		}
+		return
+	}
+
+	if *optimizer != "ga" {
+		n, err := runES(*optimizer)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		net.ToDot(n)
+		fmt.Println(n.Eval([]float64{0, 0}))
+		fmt.Println(n.Eval([]float64{0, 1}))
+		fmt.Println(n.Eval([]float64{1, 0}))
+		fmt.Println(n.Eval([]float64{1, 1}))
+		return
+	}
 
 	var ga, err = eaopt.NewDefaultGAConfig().NewGA()
 	if err != nil {
@@ -245,9 +339,27 @@ func main() {
 	ga.NGenerations = 3500
 	ga.ParallelEval = true
 	ga.HofSize = 10
+	if *metricsPath != "" {
+		f, err := os.Create(*metricsPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		if metrics, err = net.NewMetricsRecorder(f, net.MetricsFormat(*metricsPath)); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
 	// Add a custom print function to track progress
 	ga.Callback = func(ga *eaopt.GA) {
 		fmt.Printf("Best fitness at generation %d: %.30f\n", ga.Generations, ga.HallOfFame[0].Fitness)
+		if metrics != nil {
+			m := net.GAMetrics(ga, func(g eaopt.Genome) net.DNA { return net.NetToDna(g.(*Predictor).Net) })
+			if err := metrics.Record(m); err != nil {
+				fmt.Println(err)
+			}
+		}
 	}
 	ga.EarlyStop = func(ga *eaopt.GA) bool {
 		if ga.HallOfFame[0].Fitness < -42 {
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/MaxHalford/eaopt"
+	"github.com/Wouterbeets/net"
+)
+
+// countingSource is a rand.Source that counts how many numbers it handed out,
+// so its state can be saved as a seed and a count and restored by replaying
+type countingSource struct {
+	seed  int64
+	calls uint64
+	src   rand.Source64
+}
+
+func newCountingSource(seed int64, calls uint64) *countingSource {
+	s := &countingSource{seed: seed, src: rand.NewSource(seed).(rand.Source64)}
+	for s.calls < calls {
+		s.Uint64()
+	}
+	return s
+}
+
+func (s *countingSource) Int63() int64 {
+	s.calls++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.calls++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.seed = seed
+	s.calls = 0
+	s.src.Seed(seed)
+}
+
+func (s *countingSource) state() rngState {
+	return rngState{Seed: s.seed, Calls: s.calls}
+}
+
+type rngState struct {
+	Seed  int64
+	Calls uint64
+}
+
+// checkpoint is everything needed to continue a run of the GA
+type checkpoint struct {
+	Generation  uint
+	Age         time.Duration
+	RNG         rngState
+	Populations []popCheckpoint
+	HallOfFame  []indiCheckpoint
+}
+
+type popCheckpoint struct {
+	ID          string
+	Generations uint
+	Age         time.Duration
+	RNG         rngState
+	Individuals []indiCheckpoint
+}
+
+type indiCheckpoint struct {
+	ID      string
+	Fitness float64
+	DNA     net.DNA
+}
+
+// checkpointer saves the GA every so many generations, or when asked to by
+// requestSave, and restores a saved GA. eaopt always starts with a fresh
+// population, so the restore happens in the callback eaopt calls right after
+// creating it. When resuming, newGenome fills that population with
+// placeholders that aren't worth playing games for.
+//
+// The GA continues exactly where it stopped, the games themselves are random,
+// see snakeEnv.Reset, so fitnesses can differ from an uninterrupted run.
+type checkpointer struct {
+	path    string
+	every   uint
+	rng     *countingSource
+	pops    []*countingSource
+	resume  *checkpoint
+	request int32 // set by requestSave
+}
+
+// placeholder is the genome of the population eaopt creates before a
+// checkpoint replaces it
+type placeholder struct{}
+
+func (placeholder) Evaluate() (float64, error)         { return 0, nil }
+func (placeholder) Mutate(*rand.Rand)                  {}
+func (placeholder) Crossover(eaopt.Genome, *rand.Rand) {}
+func (p placeholder) Clone() eaopt.Genome              { return p }
+
+// newCheckpointer sets the GA's random number generator, if resume is set the
+// run continues from the checkpoint at path
+func newCheckpointer(ga *eaopt.GA, path string, every uint, seed int64, resume bool) (*checkpointer, error) {
+	c := &checkpointer{path: path, every: every}
+	if resume {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		c.resume = &checkpoint{}
+		if err := json.NewDecoder(f).Decode(c.resume); err != nil {
+			return nil, fmt.Errorf("unable to read checkpoint %s: %w", path, err)
+		}
+		if c.resume.Generation >= ga.NGenerations {
+			return nil, fmt.Errorf("checkpoint %s is at generation %d, the run of %d generations is done",
+				path, c.resume.Generation, ga.NGenerations)
+		}
+		ga.NGenerations -= c.resume.Generation
+	}
+	c.rng = newCountingSource(seed, 0)
+	ga.RNG = rand.New(c.rng)
+	return c, nil
+}
+
+// newGenome returns the genome factory for the GA, one making placeholders
+// when resuming
+func (c *checkpointer) newGenome(fresh func(*rand.Rand) eaopt.Genome) func(*rand.Rand) eaopt.Genome {
+	if c.resume == nil {
+		return fresh
+	}
+	return func(*rand.Rand) eaopt.Genome { return placeholder{} }
+}
+
+// requestSave makes the GA save a checkpoint at the end of the current
+// generation, it's safe to call from any goroutine
+func (c *checkpointer) requestSave() {
+	atomic.StoreInt32(&c.request, 1)
+}
+
+// saveRequested saves a checkpoint if requestSave was called since the last
+// one and checkpoints are enabled. It has to be called between generations,
+// from the GA's callback or EarlyStop.
+func (c *checkpointer) saveRequested(ga *eaopt.GA) error {
+	if c.every == 0 || atomic.LoadInt32(&c.request) == 0 {
+		return nil
+	}
+	return c.save(ga)
+}
+
+// update is called from the GA's callback
+func (c *checkpointer) update(ga *eaopt.GA) error {
+	if ga.Generations == 0 {
+		if c.resume != nil {
+			return c.restore(ga)
+		}
+		// give every population a generator whose state can be saved
+		c.pops = make([]*countingSource, len(ga.Populations))
+		for i := range ga.Populations {
+			c.pops[i] = newCountingSource(ga.RNG.Int63(), 0)
+			ga.Populations[i].RNG = rand.New(c.pops[i])
+		}
+		return nil
+	}
+	if c.every > 0 && ga.Generations%c.every == 0 {
+		return c.save(ga)
+	}
+	return c.saveRequested(ga)
+}
+
+func (c *checkpointer) restore(ga *eaopt.GA) error {
+	ck := c.resume
+	c.resume = nil
+
+	ga.Generations = ck.Generation
+	ga.Age = ck.Age
+	c.rng = newCountingSource(ck.RNG.Seed, ck.RNG.Calls)
+	ga.RNG = rand.New(c.rng)
+
+	ga.Populations = make(eaopt.Populations, len(ck.Populations))
+	c.pops = make([]*countingSource, len(ck.Populations))
+	for i, pc := range ck.Populations {
+		indis, err := restoreIndividuals(pc.Individuals)
+		if err != nil {
+			return err
+		}
+		c.pops[i] = newCountingSource(pc.RNG.Seed, pc.RNG.Calls)
+		ga.Populations[i] = eaopt.Population{
+			Individuals: indis,
+			Age:         pc.Age,
+			Generations: pc.Generations,
+			ID:          pc.ID,
+			RNG:         rand.New(c.pops[i]),
+		}
+	}
+
+	hof, err := restoreIndividuals(ck.HallOfFame)
+	if err != nil {
+		return err
+	}
+	for uint(len(hof)) < ga.HofSize {
+		hof = append(hof, eaopt.Individual{Fitness: math.Inf(1)})
+	}
+	ga.HallOfFame = hof[:ga.HofSize]
+	return nil
+}
+
+func restoreIndividuals(ics []indiCheckpoint) (eaopt.Individuals, error) {
+	indis := make(eaopt.Individuals, len(ics))
+	for i, ic := range ics {
+		n, err := net.DNAToNet(ic.DNA)
+		if err != nil {
+			return nil, err
+		}
+		indis[i] = eaopt.Individual{
+			Genome:    &Snake{Net: n},
+			Fitness:   ic.Fitness,
+			Evaluated: true,
+			ID:        ic.ID,
+		}
+	}
+	return indis, nil
+}
+
+func saveIndividuals(indis eaopt.Individuals) []indiCheckpoint {
+	ics := make([]indiCheckpoint, 0, len(indis))
+	for _, indi := range indis {
+		// empty hall of fame places have no genome
+		if indi.Genome == nil {
+			continue
+		}
+		ics = append(ics, indiCheckpoint{
+			ID:      indi.ID,
+			Fitness: indi.Fitness,
+			DNA:     net.NetToDna(indi.Genome.(*Snake).Net),
+		})
+	}
+	return ics
+}
+
+// save writes the checkpoint to a temporary file first, so a kill while
+// saving doesn't destroy the previous checkpoint
+func (c *checkpointer) save(ga *eaopt.GA) error {
+	atomic.StoreInt32(&c.request, 0)
+	ck := checkpoint{
+		Generation: ga.Generations,
+		Age:        ga.Age,
+		RNG:        c.rng.state(),
+		HallOfFame: saveIndividuals(ga.HallOfFame),
+	}
+	for i, pop := range ga.Populations {
+		ck.Populations = append(ck.Populations, popCheckpoint{
+			ID:          pop.ID,
+			Generations: pop.Generations,
+			Age:         pop.Age,
+			RNG:         c.pops[i].state(),
+			Individuals: saveIndividuals(pop.Individuals),
+		})
+	}
+
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(ck); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
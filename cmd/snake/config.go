@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Wouterbeets/net"
+)
+
+// config holds the settings of a run. They can come from a json file given
+// with -config, flags given on the command line override the file.
+type config struct {
+	Height int
+	Width  int
+	Food   int
+	// Games is the amount of games a snake is evaluated on, Rounds the
+	// maximum amount of rounds per game
+	Games  int
+	Rounds int
+
+	Hidden     int
+	HiddenKind string
+	// Optimizer is ga to evolve topology and weights, es or cmaes to only
+	// optimize the weights of a fixed topology
+	Optimizer string `json:"optimizer"`
+
+	PopSize      uint
+	Islands      uint
+	Generations  uint
+	HofSize      uint
+	Migrants     uint
+	MigFrequency uint
+	// EarlyStop stops the ga once the best fitness is below it
+	EarlyStop float64
+	// League, roundrobin or swiss, rates the snakes by playing against each
+	// other instead of alone
+	League      string
+	SwissRounds int
+
+	// Headless trains without the terminal and writes the best genome to Out
+	Headless bool
+	Out      string
+	// Record is the file the game played at the end is recorded to
+	Record string
+	// Metrics is the file per generation metrics are written to, csv for a
+	// .csv file and json lines otherwise
+	Metrics string
+	// Dashboard is the address the live dashboard is served on, e.g. :8080
+	Dashboard string
+}
+
+// inputs and outputs are fixed by the game: the sensor vision plus the
+// snake's life in, a choice out of three moves out
+const (
+	inputs  = 22
+	outputs = 3
+)
+
+var cfg = config{
+	Height:       20,
+	Width:        20,
+	Food:         1,
+	Games:        3,
+	Rounds:       1000,
+	Hidden:       5,
+	HiddenKind:   "standard",
+	Optimizer:    "ga",
+	PopSize:      1000,
+	Islands:      10,
+	Generations:  1000000,
+	HofSize:      3,
+	Migrants:     3,
+	MigFrequency: 10,
+	EarlyStop:    -100000,
+	Out:          "best.json",
+}
+
+var configPath = flag.String("config", "", "json file with the settings, flags override it")
+
+func init() {
+	flag.IntVar(&cfg.Height, "height", cfg.Height, "board height")
+	flag.IntVar(&cfg.Width, "width", cfg.Width, "board width")
+	flag.IntVar(&cfg.Food, "food", cfg.Food, "food on the board")
+	flag.IntVar(&cfg.Games, "games", cfg.Games, "games per evaluation")
+	flag.IntVar(&cfg.Rounds, "rounds", cfg.Rounds, "maximum rounds per game")
+	flag.IntVar(&cfg.Hidden, "hidden", cfg.Hidden, "hidden neurons")
+	flag.StringVar(&cfg.HiddenKind, "hidden-kind", cfg.HiddenKind, "kind of hidden neurons: standard, ctrnn, gru or lstm")
+	flag.StringVar(&cfg.Optimizer, "optimizer", cfg.Optimizer, "optimizer: ga evolves topology and weights, es and cmaes only the weights of a fixed topology")
+	flag.UintVar(&cfg.PopSize, "pop", cfg.PopSize, "population size per island")
+	flag.UintVar(&cfg.Islands, "islands", cfg.Islands, "amount of populations")
+	flag.UintVar(&cfg.Generations, "generations", cfg.Generations, "generations to evolve")
+	flag.UintVar(&cfg.HofSize, "hof", cfg.HofSize, "hall of fame size")
+	flag.UintVar(&cfg.Migrants, "migrants", cfg.Migrants, "migrants per migration")
+	flag.UintVar(&cfg.MigFrequency, "mig-frequency", cfg.MigFrequency, "generations between migrations")
+	flag.Float64Var(&cfg.EarlyStop, "early-stop", cfg.EarlyStop, "stop once the best fitness is below this")
+	flag.StringVar(&cfg.League, "league", cfg.League, "evolve snakes playing against each other with elo ratings as fitness: roundrobin or swiss")
+	flag.IntVar(&cfg.SwissRounds, "swiss-rounds", cfg.SwissRounds, "rounds of a swiss league, 0 uses log2(pop)+1")
+	flag.BoolVar(&cfg.Headless, "headless", cfg.Headless, "train without the terminal and write the best genome to -out")
+	flag.StringVar(&cfg.Out, "out", cfg.Out, "file the best genome is written to")
+	flag.StringVar(&cfg.Record, "record", cfg.Record, "file the final game is recorded to, play it back with: snake replay file")
+	flag.StringVar(&cfg.Dashboard, "dashboard", cfg.Dashboard, "address to serve a live dashboard of the training on, e.g. :8080")
+	flag.StringVar(&cfg.Metrics, "metrics", cfg.Metrics, "file per generation metrics are written to, csv for a .csv file and json lines otherwise")
+}
+
+// parseConfig parses the flags and the config file. The flags are parsed a
+// second time after reading the file so the ones on the command line win.
+func parseConfig() error {
+	flag.Parse()
+	if *configPath != "" {
+		b, err := os.ReadFile(*configPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return fmt.Errorf("unable to read config %s: %w", *configPath, err)
+		}
+		flag.Parse()
+	}
+	return cfg.validate()
+}
+
+func (c config) validate() error {
+	// the smallest board snake.NewGame accepts
+	if c.Height < 5 || c.Width < 5 {
+		return fmt.Errorf("board should be at least 5x5")
+	}
+	if c.Games < 1 || c.Rounds < 1 {
+		return fmt.Errorf("games and rounds should be > 0")
+	}
+	if c.Hidden < 1 {
+		return fmt.Errorf("hidden should be > 0")
+	}
+	if _, err := c.hiddenKind(); err != nil {
+		return err
+	}
+	switch c.Optimizer {
+	case "ga", "es", "cmaes":
+	default:
+		return fmt.Errorf("unknown optimizer %q", c.Optimizer)
+	}
+	if c.PopSize == 0 || c.Islands == 0 || c.Generations == 0 || c.HofSize == 0 {
+		return fmt.Errorf("pop, islands, generations and hof should be > 0")
+	}
+	switch c.League {
+	case "", "roundrobin", "swiss":
+	default:
+		return fmt.Errorf("unknown league %q", c.League)
+	}
+	if c.League != "" && c.PopSize < 2 {
+		return fmt.Errorf("a league needs a pop of at least 2")
+	}
+	if c.League == "roundrobin" && c.PopSize > maxRoundRobin {
+		return fmt.Errorf("a roundrobin league of %d snakes plays %d games per generation, use swiss above a pop of %d",
+			c.PopSize, c.PopSize*(c.PopSize-1)/2, maxRoundRobin)
+	}
+	if c.Islands > 1 && c.MigFrequency == 0 {
+		return fmt.Errorf("mig-frequency should be > 0")
+	}
+	return nil
+}
+
+func (c config) hiddenKind() (int, error) {
+	switch c.HiddenKind {
+	case "standard", "":
+		return net.KindStandard, nil
+	case "ctrnn":
+		return net.KindCTRNN, nil
+	case "gru":
+		return net.KindGRU, nil
+	case "lstm":
+		return net.KindLSTM, nil
+	}
+	return 0, fmt.Errorf("unknown hidden kind %q", c.HiddenKind)
+}
+
+// newNet builds a net with the configured shape
+func (c config) newNet() (*net.Net, error) {
+	kind, err := c.hiddenKind()
+	if err != nil {
+		return nil, err
+	}
+	return net.NewBuilder().Size(inputs, c.Hidden, outputs).HiddenKind(kind).Build()
+}
+
+// writeGenome writes the dna of the net as json
+func writeGenome(path string, n *net.Net) error {
+	b, err := json.MarshalIndent(net.NetToDna(n), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/Wouterbeets/net"
+	"github.com/Wouterbeets/snake"
+)
+
+// snakeEnv is a game with a single snake as a net.Environment. Every round
+// alive earns 1/Rounds and the episode ends with a reward of the longest
+// length the snake reached. A snake that survives all rounds also earns the
+// number of the episode, counting from 0, as the games always scored.
+type snakeEnv struct {
+	g       *snake.Game
+	agent   *agent
+	episode int
+	round   int
+	maxLen  int
+}
+
+// agent is the player the environment moves with the action of the runner
+type agent struct {
+	id     snake.ID
+	action []float64
+}
+
+func (a *agent) Play(g snake.GameState) snake.Move {
+	return snake.Move{Move: a.action, ID: a.id}
+}
+
+func (a *agent) SetID(id snake.ID) {
+	a.id = id
+}
+
+// Reset starts a new game. The snake package draws the food and the start of
+// the snake from the global random numbers, which it seeds from the clock, so
+// rng isn't used and the evaluation of a snake stays stochastic. NewGame only
+// fails on a board config.validate rejects.
+func (e *snakeEnv) Reset(rng *rand.Rand) {
+	if e.g != nil {
+		e.episode++
+	}
+	e.agent = &agent{}
+	g, err := snake.NewGame(cfg.Height, cfg.Width, []snake.Player{e.agent}, cfg.Food)
+	if err != nil {
+		panic(err)
+	}
+	e.g = g
+	e.round = 0
+	e.maxLen = 0
+}
+
+func (e *snakeEnv) Observe() []float64 {
+	return observe(e.g, e.agent.id)
+}
+
+func (e *snakeEnv) Step(action []float64) (reward float64, done bool) {
+	if l := e.g.PlayerLen(e.agent.id); l > e.maxLen {
+		e.maxLen = l
+	}
+	e.agent.action = action
+	gameOver, _ := e.g.PlayRound()
+	e.round++
+	done = gameOver || !e.g.Alive(e.agent.id) || e.round == cfg.Rounds
+	if !done {
+		return 1 / float64(cfg.Rounds), false
+	}
+	reward = float64(e.maxLen)
+	if !gameOver && e.g.Alive(e.agent.id) {
+		reward += float64(e.episode)
+	}
+	return reward, true
+}
+
+// observe is the input of the net: what the snake sees and its life
+func observe(g snake.GameState, id snake.ID) []float64 {
+	vis := g.Vision(id)
+	in := make([]float64, len(vis), len(vis)+1)
+	for i := range vis {
+		in[i] = float64(vis[i])
+	}
+	return append(in, g.Life(id))
+}
+
+// newRunner plays cfg.Games games of at most cfg.Rounds rounds
+func newRunner() *net.Runner {
+	r := net.NewRunner(func() net.Environment { return &snakeEnv{} })
+	r.Episodes = cfg.Games
+	r.MaxSteps = 0
+	return r
+}
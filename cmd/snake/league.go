@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/Wouterbeets/net"
+	"github.com/Wouterbeets/snake"
+)
+
+const (
+	initialRating = 1500
+	eloK          = 32
+	// maxRoundRobin is the largest pop a roundrobin league accepts, it plays
+	// n(n-1)/2 games per generation
+	maxRoundRobin = 64
+)
+
+// league rates snakes by letting them play matches against each other. Every
+// generation starts from scratch, so the ratings only compare the snakes of
+// one generation.
+type league struct {
+	// pairing is roundrobin, every snake plays every other snake, or swiss,
+	// snakes with about the same score play each other for a few rounds
+	pairing     string
+	swissRounds int
+}
+
+// match is a game between two snakes, score is 1 if a won, 0 if b won and 0.5
+// for a draw
+type match struct {
+	a, b  int
+	score float64
+}
+
+// evaluate is an Evolution.Evaluate that uses the Elo ratings as fitness
+func (l league) evaluate(dnas []net.DNA) ([]float64, error) {
+	snakes := make([]*Snake, len(dnas))
+	for i, dna := range dnas {
+		n, err := net.DNAToNet(dna)
+		if err != nil {
+			return nil, err
+		}
+		snakes[i] = &Snake{Net: n}
+	}
+	ratings, err := l.rate(snakes)
+	if err != nil {
+		return nil, err
+	}
+	fits := make([]float64, len(ratings))
+	for i, r := range ratings {
+		fits[i] = -r
+	}
+	return fits, nil
+}
+
+// rate plays the league and returns the Elo rating of every snake
+func (l league) rate(snakes []*Snake) ([]float64, error) {
+	ratings := make([]float64, len(snakes))
+	for i := range ratings {
+		ratings[i] = initialRating
+	}
+	switch l.pairing {
+	case "roundrobin":
+		for _, round := range roundRobin(len(snakes)) {
+			playRound(snakes, round, ratings)
+		}
+	case "swiss":
+		rounds := l.swissRounds
+		if rounds < 1 {
+			rounds = int(math.Ceil(math.Log2(float64(len(snakes))))) + 1
+		}
+		points := make([]float64, len(snakes))
+		played := make(map[[2]int]bool)
+		for r := 0; r < rounds; r++ {
+			round, bye := swissPairs(points, ratings, played)
+			if bye >= 0 {
+				points[bye]++
+			}
+			for _, m := range playRound(snakes, round, ratings) {
+				points[m.a] += m.score
+				points[m.b] += 1 - m.score
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown pairing %q", l.pairing)
+	}
+	return ratings, nil
+}
+
+// playRound plays the matches, which have no snake in common, in parallel and
+// updates the ratings in match order
+func playRound(snakes []*Snake, round []match, ratings []float64) []match {
+	var wg sync.WaitGroup
+	for i := range round {
+		wg.Add(1)
+		go func(m *match) {
+			defer wg.Done()
+			m.score = playMatch(snakes[m.a], snakes[m.b])
+		}(&round[i])
+	}
+	wg.Wait()
+	for _, m := range round {
+		expected := 1 / (1 + math.Pow(10, (ratings[m.b]-ratings[m.a])/400))
+		ratings[m.a] += eloK * (m.score - expected)
+		ratings[m.b] -= eloK * (m.score - expected)
+	}
+	return round
+}
+
+// playMatch returns 1 if a outlives b, 0 if b outlives a. If both die in the
+// same round or survive, the longest snake wins, if they're equally long it's
+// a draw.
+func playMatch(a, b *Snake) float64 {
+	g, err := snake.NewGame(cfg.Height, cfg.Width, []snake.Player{a, b}, cfg.Food)
+	if err != nil {
+		log.Println(err)
+		return 0.5
+	}
+	deathA, deathB := cfg.Rounds, cfg.Rounds
+	var lenA, lenB int
+	for i := 0; i < cfg.Rounds; i++ {
+		if g.Alive(a.ID) {
+			lenA = g.PlayerLen(a.ID)
+		}
+		if g.Alive(b.ID) {
+			lenB = g.PlayerLen(b.ID)
+		}
+		gameOver, _ := g.PlayRound()
+		if deathA == cfg.Rounds && !g.Alive(a.ID) {
+			deathA = i
+		}
+		if deathB == cfg.Rounds && !g.Alive(b.ID) {
+			deathB = i
+		}
+		if gameOver || (deathA < cfg.Rounds && deathB < cfg.Rounds) {
+			break
+		}
+	}
+	switch {
+	case deathA > deathB, deathA == deathB && lenA > lenB:
+		return 1
+	case deathA < deathB, deathA == deathB && lenA < lenB:
+		return 0
+	}
+	return 0.5
+}
+
+// roundRobin schedules every pair of n players in rounds where no player plays
+// twice, using the circle method
+func roundRobin(n int) [][]match {
+	players := make([]int, n)
+	for i := range players {
+		players[i] = i
+	}
+	if n%2 == 1 {
+		// -1 is the bye
+		players = append(players, -1)
+	}
+	var rounds [][]match
+	for r := 0; r < len(players)-1; r++ {
+		var round []match
+		for i := 0; i < len(players)/2; i++ {
+			a, b := players[i], players[len(players)-1-i]
+			if a >= 0 && b >= 0 {
+				round = append(round, match{a: a, b: b})
+			}
+		}
+		rounds = append(rounds, round)
+		// keep the first player in place and rotate the rest
+		last := players[len(players)-1]
+		copy(players[2:], players[1:len(players)-1])
+		players[1] = last
+	}
+	return rounds
+}
+
+// swissPairs pairs players with about the same points, avoiding rematches
+// where possible. With an odd amount of players the lowest ranked player
+// without a bye so far sits out, bye is -1 otherwise.
+func swissPairs(points, ratings []float64, played map[[2]int]bool) (round []match, bye int) {
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if points[order[i]] != points[order[j]] {
+			return points[order[i]] > points[order[j]]
+		}
+		return ratings[order[i]] > ratings[order[j]]
+	})
+	bye = -1
+	if len(order)%2 == 1 {
+		for i := len(order) - 1; i >= 0; i-- {
+			if !played[[2]int{order[i], order[i]}] {
+				bye = order[i]
+				break
+			}
+		}
+		if bye < 0 {
+			bye = order[len(order)-1]
+		}
+		played[[2]int{bye, bye}] = true
+	}
+
+	paired := make(map[int]bool)
+	for i, a := range order {
+		if a == bye || paired[a] {
+			continue
+		}
+		b := -1
+		for _, c := range order[i+1:] {
+			if c == bye || paired[c] {
+				continue
+			}
+			if b < 0 {
+				// rematch if there's nobody else
+				b = c
+			}
+			if !played[[2]int{a, c}] {
+				b = c
+				break
+			}
+		}
+		if b < 0 {
+			break
+		}
+		paired[a], paired[b] = true, true
+		played[[2]int{a, b}], played[[2]int{b, a}] = true, true
+		round = append(round, match{a: a, b: b})
+	}
+	return round, bye
+}
+
+// runLeague co-evolves snakes that are rated by playing against each other
+func runLeague(pairing string) ([]snake.Player, error) {
+	e := net.NewEvolution(nil)
+	e.PopSize = int(cfg.PopSize)
+	e.Generations = int(cfg.Generations)
+	e.Elitism = int(cfg.HofSize)
+	if *seed != 0 {
+		e.Seed = *seed
+	}
+	e.Evaluate = league{pairing: pairing, swissRounds: cfg.SwissRounds}.evaluate
+	if recording() {
+		e.Mutate = mutations.Wrap((*net.DNA).MutateAll)
+	}
+	e.Callback = func(e *net.Evolution) {
+		log.Printf("gen: %d\tbest rating: %.1f\tworst rating: %.1f",
+			e.Generation,
+			-e.Population[0].Fitness,
+			-e.Population[len(e.Population)-1].Fitness,
+		)
+		recordEvolution(e)
+	}
+	var newErr error
+	err := e.Minimize(func(rng *rand.Rand) net.DNA {
+		n, err := cfg.newNet()
+		if err != nil {
+			newErr = err
+			return net.DNA{}
+		}
+		return net.NetToDna(n)
+	})
+	if newErr != nil {
+		return nil, newErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var players []snake.Player
+	for i, indi := range e.Population {
+		if i == int(cfg.HofSize) {
+			break
+		}
+		n, err := net.DNAToNet(indi.DNA)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, &Snake{Net: n})
+	}
+	return players, nil
+}
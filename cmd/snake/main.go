@@ -1,11 +1,13 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -29,14 +31,7 @@ func (p *Snake) Size() float64 {
 }
 
 func (s *Snake) Play(g snake.GameState) snake.Move {
-	vis := g.Vision(s.ID)
-	life := g.Life(s.ID)
-	in := make([]float64, len(vis))
-	in = append(in, life)
-	for i := range vis {
-		in[i] = float64(vis[i])
-	}
-	out, err := s.Net.Eval(in)
+	out, err := s.Net.Eval(observe(g, s.ID))
 	if err != nil {
 		fmt.Println(err.Error())
 	}
@@ -47,36 +42,16 @@ func (s *Snake) SetID(id snake.ID) {
 	s.ID = id
 }
 
+// Evaluate plays cfg.Games games alone, the fitness is the negated sum of
+// their returns. The games are random, see snakeEnv.Reset.
 func (s *Snake) Evaluate() (float64, error) {
-
-	gameRounds := 3
-	rounds := 1000
+	returns, err := newRunner().Run(s.Net)
+	if err != nil {
+		return 0, err
+	}
 	var score float64
-	for gr := 0; gr < gameRounds; gr++ {
-		g, err := snake.NewGame(20, 20, []snake.Player{
-			s,
-		}, 1)
-		if err != nil {
-			fmt.Println(err.Error())
-		}
-		var snakeLen int
-		var maxLen int
-		for i := 0; i < rounds; i++ {
-			snakeLen = g.PlayerLen(s.ID)
-			if snakeLen > maxLen {
-				maxLen = snakeLen
-			}
-			gameOver, _ := g.PlayRound()
-			if gameOver || !g.Alive(s.ID) {
-				score += (float64(i)/float64(rounds) + float64(maxLen))
-				break
-			}
-			if i == rounds-1 {
-				score += (float64(i)/float64(rounds) + float64(maxLen))
-				score += float64(gr)
-			}
-		}
-
+	for _, r := range returns {
+		score += r
 	}
 	return -score, nil
 }
@@ -84,7 +59,14 @@ func (s *Snake) Evaluate() (float64, error) {
 func (s *Snake) Mutate(rng *rand.Rand) {
 	dna := net.NetToDna(s.Net)
 
+	var before net.DNA
+	if recording() {
+		before = dna.Clone()
+	}
 	dna.Mutate(rng)
+	if recording() {
+		mutations.Record(before, dna)
+	}
 
 	n, err := net.DNAToNet(dna)
 	if err != nil {
@@ -125,16 +107,20 @@ func (p *Snake) Clone() eaopt.Genome {
 	return &Snake{Net: n}
 }
 
-func NewSnake(rng *rand.Rand) eaopt.Genome {
-	n, err := net.NewBuilder().Size(22, 5, 3).Build()
+// NewSnake returns a snake with a new net of the configured shape
+func NewSnake(rng *rand.Rand) (*Snake, error) {
+	n, err := cfg.newNet()
 	if err != nil {
-		fmt.Println("error making new nn")
+		return nil, err
 	}
-	return &Snake{Net: n}
+	return &Snake{Net: n}, nil
 }
 
-func sig(sigs chan os.Signal, ga *eaopt.GA) {
+// sig shows a game against the hall of fame on every signal, the GA saves a
+// checkpoint at the end of the generation
+func sig(sigs chan os.Signal, ga *eaopt.GA, ck *checkpointer) {
 	<-sigs
+	ck.requestSave()
 	net.ToDot(ga.HallOfFame[0].Genome.(*Snake).Net)
 
 	framerate := 50 * time.Millisecond
@@ -145,7 +131,7 @@ func sig(sigs chan os.Signal, ga *eaopt.GA) {
 	for _, n := range ga.HallOfFame {
 		players = append(players, n.Genome.(*Snake))
 	}
-	g, err := snake.NewGame(20, 20, players, 1)
+	g, err := snake.NewGame(cfg.Height, cfg.Width, players, cfg.Food)
 	if err != nil {
 		panic(err)
 	}
@@ -164,18 +150,59 @@ func sig(sigs chan os.Signal, ga *eaopt.GA) {
 		gameOver, state := g.PlayRound()
 		sc.Input <- stateToRune(state, runes)
 		if gameOver {
-			go sig(sigs, ga)
+			go sig(sigs, ga, ck)
 			return
 		}
 	}
-	go sig(sigs, ga)
+	go sig(sigs, ga, ck)
 }
 
-func main() {
-	var ga, err = eaopt.NewDefaultGAConfig().NewGA()
+var (
+	checkpointPath  = flag.String("checkpoint", "snake.checkpoint.json", "file the ga checkpoints are written to")
+	checkpointEvery = flag.Uint("checkpoint-every", 10, "generations between checkpoints, 0 disables them")
+	resume          = flag.Bool("resume", false, "continue the run saved in the checkpoint file")
+	seed            = flag.Int64("seed", 0, "seed of the ga, 0 uses the time")
+)
+
+// runES optimizes the weights of a fixed topology net with an evolution
+// strategy
+func runES(kind string) (*net.Net, error) {
+	n, err := cfg.newNet()
 	if err != nil {
+		return nil, err
+	}
+	fitness := func(n *net.Net) (float64, error) {
+		return (&Snake{Net: n}).Evaluate()
+	}
+	err = net.OptimizeParams(n, kind, int(cfg.Generations), fitness, func(gen int, best float64) {
+		log.Printf("gen: %d\tbest: %.6f", gen, best)
+	})
+	return n, err
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		var cmd func([]string) error
+		switch os.Args[1] {
+		case "record":
+			cmd = recordCmd
+		case "replay":
+			cmd = replayCmd
+		case "saliency":
+			cmd = saliencyCmd
+		}
+		if cmd != nil {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	if err := parseConfig(); err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(2)
 	}
 
 	f, err := os.Create("log.txt")
@@ -184,17 +211,67 @@ func main() {
 	}
 	l := log.Default()
 	l.SetOutput(f)
-	ga.Logger = l
 
-	sigs := make(chan os.Signal, 1)
+	if cfg.Metrics != "" {
+		closeMetrics, err := openMetrics(cfg.Metrics)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer closeMetrics()
+	}
+	if cfg.Dashboard != "" {
+		serveDashboard(cfg.Dashboard)
+	}
 
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	if cfg.League != "" {
+		players, err := runLeague(cfg.League)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if cfg.Headless {
+			if err := writeGenome(cfg.Out, players[0].(*Snake).Net); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+		net.ToDot(players[0].(*Snake).Net)
+		play(players)
+		return
+	}
+
+	if cfg.Optimizer != "ga" {
+		n, err := runES(cfg.Optimizer)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if cfg.Headless {
+			if err := writeGenome(cfg.Out, n); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+		net.ToDot(n)
+		play([]snake.Player{&Snake{Net: n}})
+		return
+	}
 
-	ga.PopSize = 1000
-	ga.NPops = 10
-	ga.NGenerations = 1000000
+	ga, err := eaopt.NewDefaultGAConfig().NewGA()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ga.Logger = l
+
+	ga.PopSize = cfg.PopSize
+	ga.NPops = cfg.Islands
+	ga.NGenerations = cfg.Generations
 	ga.ParallelEval = true
-	ga.HofSize = 3
+	ga.HofSize = cfg.HofSize
 	ga.Model = eaopt.ModDownToSize{
 		NOffsprings: 2,
 		SelectorA: eaopt.SelTournament{
@@ -204,10 +281,24 @@ func main() {
 		MutRate:   1,
 		CrossRate: 1,
 	}
-	ga.Migrator = eaopt.MigRing{NMigrants: 3}
-	ga.MigFrequency = 10
+	if cfg.Islands > 1 {
+		ga.Migrator = eaopt.MigRing{NMigrants: cfg.Migrants}
+		ga.MigFrequency = cfg.MigFrequency
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	ck, err := newCheckpointer(ga, *checkpointPath, *checkpointEvery, *seed, *resume)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	// Add a custom print function to track progress
 	ga.Callback = func(ga *eaopt.GA) {
+		if err := ck.update(ga); err != nil {
+			log.Printf("checkpoint failed: %s", err)
+		}
 		var (
 			distMin   float64
 			distMax   float64
@@ -233,49 +324,85 @@ func main() {
 			distSum/float64(len(ga.Populations)),
 			popAvgSum/float64(len(ga.Populations)),
 		)
+		recordGA(ga)
 	}
+	// stop is set when a signal stops a headless run
+	var stop int32
 	ga.EarlyStop = func(ga *eaopt.GA) bool {
-		if ga.HallOfFame[0].Fitness < -100000 {
+		if atomic.LoadInt32(&stop) == 1 {
+			if err := ck.saveRequested(ga); err != nil {
+				log.Printf("checkpoint failed: %s", err)
+			}
+			return true
+		}
+		if ga.HallOfFame[0].Fitness < cfg.EarlyStop {
 			return true
 		}
 		return false
 	}
 
-	go sig(sigs, ga)
-	err = ga.Minimize(NewSnake)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	if cfg.Headless {
+		// finish the generation, save a checkpoint and write the best genome
+		go func() {
+			<-sigs
+			atomic.StoreInt32(&stop, 1)
+			ck.requestSave()
+			fmt.Println("stopping after this generation, signal again to quit now")
+			<-sigs
+			os.Exit(1)
+		}()
+	} else {
+		go sig(sigs, ga, ck)
+	}
+	err = ga.Minimize(ck.newGenome(func(rng *rand.Rand) eaopt.Genome {
+		s, err := NewSnake(rng)
+		if err != nil {
+			// config.validate only lets through shapes the Builder accepts
+			panic(err)
+		}
+		return s
+	}))
 	if err != nil {
 		fmt.Println(err)
 	}
+	if cfg.Headless {
+		if err := writeGenome(cfg.Out, ga.HallOfFame[0].Genome.(*Snake).Net); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 	net.ToDot(ga.HallOfFame[0].Genome.(*Snake).Net)
 
+	var players []snake.Player
+	for _, n := range ga.HallOfFame {
+		players = append(players, n.Genome.(*Snake))
+	}
+	play(players)
+}
+
+// play lets a human play against the snakes in the terminal
+func play(snakes []snake.Player) {
 	framerate := 50 * time.Millisecond
 	sc := term.Screen{Input: make(chan [][]rune), UserInput: make(chan rune)}
 	players := []snake.Player{
 		&snake.Human{Input: sc.UserInput, Framerate: framerate},
 	}
-	for _, n := range ga.HallOfFame {
-		players = append(players, n.Genome.(*Snake))
-	}
-	g, err := snake.NewGame(20, 20, players, 1)
-	if err != nil {
-		panic(err)
-	}
+	players = append(players, snakes...)
 	go sc.Run(framerate)
 
-	runes := map[int8]rune{
-		-1: 'M',
-		0:  ' ',
-		1:  '█',
-		2:  'X',
-	}
-	for i := range players {
-		runes[int8(i)+3] = '█'
-	}
-	for i := 0; i < 100000; i++ {
-		gameOver, state := g.PlayRound()
+	runes := boardRunes(len(players))
+	rep, err := recordGame(players, 100000, func(state snake.Board) {
 		sc.Input <- stateToRune(state, runes)
-		if gameOver {
-			return
+	})
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Record != "" {
+		if err := rep.save(cfg.Record); err != nil {
+			log.Printf("unable to save replay: %s", err)
 		}
 	}
 }
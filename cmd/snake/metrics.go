@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/MaxHalford/eaopt"
+	"github.com/Wouterbeets/net"
+)
+
+// metrics records a line per generation when -metrics is set, dashboard
+// serves the progress when -dashboard is set, they're nil otherwise
+var (
+	metrics   *net.MetricsRecorder
+	dashboard *net.Dashboard
+	// mutations counts the mutations for both
+	mutations net.MutationCounter
+)
+
+// recording is true if the generations are recorded by metrics or dashboard
+func recording() bool {
+	return metrics != nil || dashboard != nil
+}
+
+// openMetrics starts recording to path, the returned func closes the file
+func openMetrics(path string) (func() error, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err = net.NewMetricsRecorder(f, net.MetricsFormat(path))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f.Close, nil
+}
+
+// serveDashboard serves the dashboard on addr in the background
+func serveDashboard(addr string) {
+	dashboard = net.NewDashboard()
+	go func() {
+		if err := dashboard.ListenAndServe(addr); err != nil {
+			log.Printf("dashboard stopped: %s", err)
+		}
+	}()
+}
+
+// recordGA records the current generation of the ga
+func recordGA(ga *eaopt.GA) {
+	if !recording() {
+		return
+	}
+	toDNA := func(g eaopt.Genome) net.DNA { return net.NetToDna(g.(*Snake).Net) }
+	record(net.GAMetrics(ga, toDNA), toDNA(ga.HallOfFame[0].Genome), ga.HallOfFame[0].Fitness)
+}
+
+// recordEvolution records the current generation of the evolution
+func recordEvolution(e *net.Evolution) {
+	if !recording() {
+		return
+	}
+	record(net.EvolutionMetrics(e), e.Best.DNA, e.Best.Fitness)
+}
+
+func record(m net.GenerationMetrics, best net.DNA, fitness float64) {
+	m.Mutations = mutations.Take()
+	if metrics != nil {
+		if err := metrics.Record(m); err != nil {
+			log.Printf("unable to record metrics: %s", err)
+		}
+	}
+	if dashboard != nil {
+		if err := dashboard.Update(m, best, fitness); err != nil {
+			log.Printf("unable to update dashboard: %s", err)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Wouterbeets/net"
+	"github.com/Wouterbeets/snake"
+	"github.com/wouterbeets/term"
+)
+
+// replay is a recorded game
+type replay struct {
+	Height int
+	Width  int
+	Frames []frame
+}
+
+// frame is the board after a round and what every recorded snake did in it
+type frame struct {
+	Board snake.Board
+	Ticks []tick
+}
+
+// tick is one move of a snake with the output and activations of its net
+type tick struct {
+	ID          snake.ID
+	Alive       bool
+	Len         int
+	Output      []float64
+	Activations map[int]float64
+}
+
+// recorder is a player that remembers the last move of the snake it wraps
+type recorder struct {
+	*Snake
+	last tick
+}
+
+func (r *recorder) Play(g snake.GameState) snake.Move {
+	m := r.Snake.Play(g)
+	r.last = tick{
+		ID:          r.Snake.ID,
+		Output:      append([]float64(nil), m.Move...),
+		Activations: r.Snake.Net.Activations(),
+	}
+	return m
+}
+
+// recordGame plays the players until the game is over or rounds have been
+// played, the snakes are recorded and show is called with every board
+func recordGame(players []snake.Player, rounds int, show func(snake.Board)) (*replay, error) {
+	for i, p := range players {
+		if s, ok := p.(*Snake); ok {
+			players[i] = &recorder{Snake: s}
+		}
+	}
+	g, err := snake.NewGame(cfg.Height, cfg.Width, players, cfg.Food)
+	if err != nil {
+		return nil, err
+	}
+	rep := &replay{Height: cfg.Height, Width: cfg.Width}
+	for i := 0; i < rounds; i++ {
+		gameOver, board := g.PlayRound()
+		f := frame{Board: copyBoard(board)}
+		for _, p := range players {
+			if r, ok := p.(*recorder); ok {
+				t := r.last
+				t.Alive = g.Alive(r.Snake.ID)
+				t.Len = g.PlayerLen(r.Snake.ID)
+				f.Ticks = append(f.Ticks, t)
+			}
+		}
+		rep.Frames = append(rep.Frames, f)
+		if show != nil {
+			show(board)
+		}
+		if gameOver {
+			break
+		}
+	}
+	return rep, nil
+}
+
+func copyBoard(b snake.Board) snake.Board {
+	c := make(snake.Board, len(b))
+	for i := range b {
+		c[i] = append([]int8(nil), b[i]...)
+	}
+	return c
+}
+
+func (rep *replay) save(path string) error {
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func loadReplay(path string) (*replay, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rep := &replay{}
+	if err := json.Unmarshal(b, rep); err != nil {
+		return nil, fmt.Errorf("unable to read replay %s: %w", path, err)
+	}
+	if len(rep.Frames) == 0 {
+		return nil, fmt.Errorf("replay %s has no frames", path)
+	}
+	return rep, nil
+}
+
+// loadSnake reads a genome written by writeGenome
+func loadSnake(path string) (*Snake, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dna net.DNA
+	if err := json.Unmarshal(b, &dna); err != nil {
+		return nil, fmt.Errorf("unable to read genome %s: %w", path, err)
+	}
+	n, err := net.DNAToNet(dna)
+	if err != nil {
+		return nil, err
+	}
+	return &Snake{Net: n}, nil
+}
+
+// recordCmd plays a game of the given genomes without a terminal and writes
+// the replay
+func recordCmd(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	out := fs.String("o", "game.replay.json", "replay file")
+	rounds := fs.Int("rounds", cfg.Rounds, "maximum rounds")
+	trace := fs.String("trace", "", "csv file every neuron of the first snake is traced to, every round")
+	fs.IntVar(&cfg.Height, "height", cfg.Height, "board height")
+	fs.IntVar(&cfg.Width, "width", cfg.Width, "board width")
+	fs.IntVar(&cfg.Food, "food", cfg.Food, "food on the board")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snake record [flags] genome.json...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no genomes given")
+	}
+	var players []snake.Player
+	for _, path := range fs.Args() {
+		s, err := loadSnake(path)
+		if err != nil {
+			return err
+		}
+		players = append(players, s)
+	}
+	tracer := &net.TraceRecorder{}
+	if *trace != "" {
+		players[0].(*Snake).Net.SetTracer(tracer)
+	}
+	rep, err := recordGame(players, *rounds, nil)
+	if err != nil {
+		return err
+	}
+	if *trace != "" {
+		if err := writeTrace(*trace, tracer); err != nil {
+			return err
+		}
+	}
+	return rep.save(*out)
+}
+
+func writeTrace(path string, tracer *net.TraceRecorder) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tracer.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replayCmd plays a replay back in the terminal. Space pauses, n and b step
+// forward and back while paused, + and - change the speed and q quits.
+// With -tick the frame is printed instead, activations included.
+func replayCmd(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := fs.Duration("speed", 100*time.Millisecond, "time between frames")
+	inspect := fs.Int("tick", -1, "print this frame with the nets' outputs and activations and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snake replay [flags] game.replay.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one replay file")
+	}
+	rep, err := loadReplay(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *inspect >= 0 {
+		if *inspect >= len(rep.Frames) {
+			return fmt.Errorf("tick %d out of range, the replay has %d", *inspect, len(rep.Frames))
+		}
+		printFrame(rep.Frames[*inspect], *inspect)
+		return nil
+	}
+	playback(rep, *speed)
+	return nil
+}
+
+func printFrame(f frame, i int) {
+	runes := boardRunes(len(f.Ticks) + 1)
+	fmt.Printf("tick %d\n", i)
+	for _, row := range stateToRune(f.Board, runes) {
+		fmt.Println(string(row))
+	}
+	for _, t := range f.Ticks {
+		fmt.Printf("snake %d alive: %t len: %d output: %.4f\n", t.ID, t.Alive, t.Len, t.Output)
+		for _, id := range sortedIDs(t.Activations) {
+			fmt.Printf("\t%d\t%.6f\n", id, t.Activations[id])
+		}
+	}
+}
+
+// minFrameTime is the fastest playback, + stops halving the time there
+const minFrameTime = time.Millisecond
+
+func playback(rep *replay, speed time.Duration) {
+	if speed < minFrameTime {
+		speed = minFrameTime
+	}
+	sc := term.Screen{Input: make(chan [][]rune), UserInput: make(chan rune)}
+	go sc.Run(10 * time.Millisecond)
+	defer func() { sc.Input <- nil }()
+
+	var players int
+	for _, f := range rep.Frames {
+		if len(f.Ticks) > players {
+			players = len(f.Ticks)
+		}
+	}
+	runes := boardRunes(players + 1)
+
+	i := 0
+	paused := false
+	timer := time.NewTimer(speed)
+	for {
+		sc.Input <- renderFrame(rep, i, runes, paused)
+		select {
+		case key := <-sc.UserInput:
+			switch key {
+			case ' ':
+				paused = !paused
+			case 'n':
+				if i < len(rep.Frames)-1 {
+					i++
+				}
+			case 'b':
+				if i > 0 {
+					i--
+				}
+			case '+':
+				speed /= 2
+				if speed < minFrameTime {
+					speed = minFrameTime
+				}
+			case '-':
+				speed *= 2
+			case 'q':
+				return
+			}
+		case <-timer.C:
+			timer.Reset(speed)
+			if !paused && i < len(rep.Frames)-1 {
+				i++
+			}
+		}
+	}
+}
+
+// activationRunes shade an activation from -1 to 1
+var activationRunes = []rune(" ░▒▓█")
+
+// renderFrame draws the board with below it a row per snake with the shaded
+// activations of its net and a progress bar
+func renderFrame(rep *replay, i int, runes map[int8]rune, paused bool) [][]rune {
+	f := rep.Frames[i]
+	disp := stateToRune(f.Board, runes)
+	for _, t := range f.Ticks {
+		row := []rune{runes[int8(t.ID)], ' '}
+		for _, id := range sortedIDs(t.Activations) {
+			v := (math.Max(-1, math.Min(1, t.Activations[id])) + 1) / 2
+			row = append(row, activationRunes[int(v*float64(len(activationRunes)-1)+0.5)])
+		}
+		disp = append(disp, row)
+	}
+	progress := make([]rune, rep.Width)
+	for x := range progress {
+		progress[x] = '-'
+		if x <= i*(rep.Width-1)/max(len(rep.Frames)-1, 1) {
+			progress[x] = '='
+		}
+	}
+	if paused {
+		progress = append(progress, ' ', '|', '|')
+	}
+	return append(disp, progress)
+}
+
+func boardRunes(players int) map[int8]rune {
+	runes := map[int8]rune{
+		-1: 'M',
+		0:  ' ',
+		1:  '█',
+		2:  'X',
+	}
+	for i := 0; i < players; i++ {
+		runes[int8(i)+3] = '█'
+	}
+	return runes
+}
+
+func sortedIDs(acts map[int]float64) []int {
+	ids := make([]int, 0, len(acts))
+	for id := range acts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Wouterbeets/net"
+	"github.com/Wouterbeets/snake"
+)
+
+// saliencyCmd plays games with a genome and writes how much its moves depend
+// on every input, averaged over all rounds played
+func saliencyCmd(args []string) error {
+	fs := flag.NewFlagSet("saliency", flag.ExitOnError)
+	out := fs.String("o", "", "csv file, stdout if empty")
+	games := fs.Int("games", cfg.Games, "games to play")
+	rounds := fs.Int("rounds", cfg.Rounds, "maximum rounds per game")
+	fs.IntVar(&cfg.Height, "height", cfg.Height, "board height")
+	fs.IntVar(&cfg.Width, "width", cfg.Width, "board width")
+	fs.IntVar(&cfg.Food, "food", cfg.Food, "food on the board")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snake saliency [flags] genome.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one genome")
+	}
+
+	var total []net.InputSensitivity
+	samples := 0
+	for g := 0; g < *games; g++ {
+		s, err := loadSnake(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		tracer := &net.TraceRecorder{}
+		s.Net.SetTracer(tracer)
+		if _, err := recordGame([]snake.Player{s}, *rounds, nil); err != nil {
+			return err
+		}
+		var episode [][]float64
+		for _, t := range tracer.Traces() {
+			// Eval only reads as many inputs as the net has
+			if len(t.Input) > s.Net.InSize() {
+				t.Input = t.Input[:s.Net.InSize()]
+			}
+			episode = append(episode, t.Input)
+		}
+
+		// analyse the game from the start, with a net that hasn't played it
+		if s, err = loadSnake(fs.Arg(0)); err != nil {
+			return err
+		}
+		sens, err := net.Sensitivity(s.Net, episode)
+		if err != nil {
+			return err
+		}
+		if total == nil {
+			total = make([]net.InputSensitivity, len(sens))
+		}
+		for i, is := range sens {
+			total[i].Input = i
+			total[i].Saliency += is.Saliency * float64(len(episode))
+			total[i].Ablation += is.Ablation * float64(len(episode))
+		}
+		samples += len(episode)
+	}
+	if samples > 0 {
+		for i := range total {
+			total[i].Saliency /= float64(samples)
+			total[i].Ablation /= float64(samples)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return net.WriteSensitivityCSV(w, total)
+}
@@ -0,0 +1,361 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Classic control benchmarks as Environments. They have no dependencies and
+// are deterministic given the rng passed to Reset, so the Runners below give
+// the same fitness for the same net every time.
+
+// CartPole balances one or two poles on a cart by pushing it left or right,
+// following the double pole model of Wieland as used in the NEAT experiments.
+// The first action value in [-1, 1] is the force, every step balanced earns 1.
+// Without velocity the net only sees positions and angles, so it needs memory
+// to balance.
+type CartPole struct {
+	Poles    int
+	Velocity bool
+
+	x, dx     float64
+	theta     []float64
+	dtheta    []float64
+	mass, len []float64 // of the poles, len is half the pole length
+}
+
+const (
+	cartMass       = 1.0
+	cartForce      = 10.0
+	cartFriction   = 5e-4
+	poleFriction   = 2e-6
+	cartGravity    = -9.8
+	cartTimeStep   = 0.01
+	cartTrackLimit = 2.4
+)
+
+// NewCartPole returns a cart with 1 or 2 poles
+func NewCartPole(poles int, velocity bool) (*CartPole, error) {
+	if poles != 1 && poles != 2 {
+		return nil, fmt.Errorf("a cart has 1 or 2 poles, got %d", poles)
+	}
+	c := &CartPole{Poles: poles, Velocity: velocity}
+	c.theta = make([]float64, poles)
+	c.dtheta = make([]float64, poles)
+	c.mass = []float64{0.1, 0.01}[:poles]
+	c.len = []float64{0.5, 0.05}[:poles]
+	return c, nil
+}
+
+// CartPoleRunner is the reference fitness of a cart pole, balancing for 1000
+// steps is a fitness of -1000
+func CartPoleRunner(poles int, velocity bool) (*Runner, error) {
+	if _, err := NewCartPole(poles, velocity); err != nil {
+		return nil, err
+	}
+	r := NewRunner(func() Environment {
+		c, _ := NewCartPole(poles, velocity)
+		return c
+	})
+	r.Episodes = 3
+	r.MaxSteps = 1000
+	r.Action = ContinuousAction(-1, 1)
+	return r, nil
+}
+
+// InSize is the amount of observations
+func (c *CartPole) InSize() int {
+	if c.Velocity {
+		return 2 + 2*c.Poles
+	}
+	return 1 + c.Poles
+}
+
+func (c *CartPole) Reset(rng *rand.Rand) {
+	c.theta = make([]float64, c.Poles)
+	c.dtheta = make([]float64, c.Poles)
+	if c.Poles == 1 {
+		c.x = rng.Float64()*0.1 - 0.05
+		c.dx = rng.Float64()*0.1 - 0.05
+		c.theta[0] = rng.Float64()*0.1 - 0.05
+		c.dtheta[0] = rng.Float64()*0.1 - 0.05
+		return
+	}
+	// the classic double pole start, the long pole at 4 degrees
+	c.x, c.dx = 0, 0
+	c.theta[0] = 4 * math.Pi / 180
+}
+
+func (c *CartPole) Observe() []float64 {
+	obs := []float64{c.x / cartTrackLimit}
+	if c.Velocity {
+		obs = append(obs, c.dx/2)
+	}
+	for i := range c.theta {
+		obs = append(obs, c.theta[i]/c.failAngle())
+		if c.Velocity {
+			obs = append(obs, c.dtheta[i]/2)
+		}
+	}
+	return obs
+}
+
+func (c *CartPole) Step(action []float64) (float64, bool) {
+	force := 0.0
+	if len(action) > 0 {
+		force = math.Max(-1, math.Min(1, action[0])) * cartForce
+	}
+	// two integration steps per action, like the NEAT experiments
+	for i := 0; i < 2; i++ {
+		c.rk4(force)
+	}
+	if math.Abs(c.x) > cartTrackLimit {
+		return 0, true
+	}
+	for _, theta := range c.theta {
+		if math.Abs(theta) > c.failAngle() {
+			return 0, true
+		}
+	}
+	return 1, false
+}
+
+func (c *CartPole) failAngle() float64 {
+	if c.Poles == 1 {
+		return 12 * math.Pi / 180
+	}
+	return 36 * math.Pi / 180
+}
+
+// state is x, dx, theta_i, dtheta_i
+func (c *CartPole) state() []float64 {
+	s := []float64{c.x, c.dx}
+	for i := range c.theta {
+		s = append(s, c.theta[i], c.dtheta[i])
+	}
+	return s
+}
+
+func (c *CartPole) setState(s []float64) {
+	c.x, c.dx = s[0], s[1]
+	for i := range c.theta {
+		c.theta[i], c.dtheta[i] = s[2+2*i], s[3+2*i]
+	}
+}
+
+// derivs returns the derivative of the state
+func (c *CartPole) derivs(force float64, s []float64) []float64 {
+	d := make([]float64, len(s))
+	var forces, masses float64
+	for i := range c.theta {
+		theta, dtheta := s[2+2*i], s[3+2*i]
+		ml := c.mass[i] * c.len[i]
+		friction := poleFriction * dtheta / ml
+		forces += ml*dtheta*dtheta*math.Sin(theta) +
+			0.75*c.mass[i]*math.Cos(theta)*(friction+cartGravity*math.Sin(theta))
+		masses += c.mass[i] * (1 - 0.75*math.Cos(theta)*math.Cos(theta))
+	}
+	sign := 0.0
+	if s[1] > 0 {
+		sign = 1
+	} else if s[1] < 0 {
+		sign = -1
+	}
+	ddx := (force - cartFriction*sign + forces) / (cartMass + masses)
+	d[0], d[1] = s[1], ddx
+	for i := range c.theta {
+		theta, dtheta := s[2+2*i], s[3+2*i]
+		friction := poleFriction * dtheta / (c.mass[i] * c.len[i])
+		d[2+2*i] = dtheta
+		d[3+2*i] = -0.75 * (ddx*math.Cos(theta) + cartGravity*math.Sin(theta) + friction) / c.len[i]
+	}
+	return d
+}
+
+func (c *CartPole) rk4(force float64) {
+	c.setState(rk4(c.state(), cartTimeStep, func(s []float64) []float64 { return c.derivs(force, s) }))
+}
+
+// rk4 integrates the state over dt with the classic Runge-Kutta method
+func rk4(s []float64, dt float64, derivs func([]float64) []float64) []float64 {
+	add := func(a, b []float64, f float64) []float64 {
+		out := make([]float64, len(a))
+		for i := range a {
+			out[i] = a[i] + f*b[i]
+		}
+		return out
+	}
+	k1 := derivs(s)
+	k2 := derivs(add(s, k1, dt/2))
+	k3 := derivs(add(s, k2, dt/2))
+	k4 := derivs(add(s, k3, dt))
+	out := make([]float64, len(s))
+	for i := range s {
+		out[i] = s[i] + dt/6*(k1[i]+2*k2[i]+2*k3[i]+k4[i])
+	}
+	return out
+}
+
+// MountainCar drives an underpowered car out of a valley, it has to swing
+// back and forth to build up momentum. The largest of three action values
+// pushes left, does nothing or pushes right. Every step costs 1 until the car
+// reaches the flag.
+type MountainCar struct {
+	pos, vel float64
+}
+
+// MountainCarRunner is the reference fitness of the mountain car, the amount
+// of steps needed to reach the flag, at most 200
+func MountainCarRunner() *Runner {
+	r := NewRunner(func() Environment { return &MountainCar{} })
+	r.Episodes = 3
+	r.MaxSteps = 200
+	r.Action = ArgmaxAction
+	return r
+}
+
+func (m *MountainCar) Reset(rng *rand.Rand) {
+	m.pos = -0.6 + rng.Float64()*0.2
+	m.vel = 0
+}
+
+func (m *MountainCar) Observe() []float64 {
+	return []float64{(m.pos + 0.3) / 0.9, m.vel / 0.07}
+}
+
+func (m *MountainCar) Step(action []float64) (float64, bool) {
+	push := float64(Discrete(action) - 1)
+	m.vel += push*0.001 - 0.0025*math.Cos(3*m.pos)
+	m.vel = math.Max(-0.07, math.Min(0.07, m.vel))
+	m.pos += m.vel
+	if m.pos < -1.2 {
+		m.pos, m.vel = -1.2, 0
+	}
+	return -1, m.pos >= 0.5
+}
+
+// Acrobot swings up a two link pendulum that is only actuated at the joint
+// between the links. The largest of three action values applies a torque of
+// -1, 0 or 1. Every step costs 1 until the tip is a link's length above the
+// pivot.
+type Acrobot struct {
+	theta1, theta2   float64
+	dtheta1, dtheta2 float64
+}
+
+const acrobotTimeStep = 0.2
+
+// AcrobotRunner is the reference fitness of the acrobot, the amount of steps
+// needed to swing up, at most 500
+func AcrobotRunner() *Runner {
+	r := NewRunner(func() Environment { return &Acrobot{} })
+	r.Episodes = 3
+	r.MaxSteps = 500
+	r.Action = ArgmaxAction
+	return r
+}
+
+func (a *Acrobot) Reset(rng *rand.Rand) {
+	r := func() float64 { return rng.Float64()*0.2 - 0.1 }
+	a.theta1, a.theta2, a.dtheta1, a.dtheta2 = r(), r(), r(), r()
+}
+
+func (a *Acrobot) Observe() []float64 {
+	return []float64{
+		math.Cos(a.theta1), math.Sin(a.theta1),
+		math.Cos(a.theta2), math.Sin(a.theta2),
+		a.dtheta1 / (4 * math.Pi), a.dtheta2 / (9 * math.Pi),
+	}
+}
+
+func (a *Acrobot) Step(action []float64) (float64, bool) {
+	torque := float64(Discrete(action) - 1)
+	s := rk4([]float64{a.theta1, a.theta2, a.dtheta1, a.dtheta2}, acrobotTimeStep, func(s []float64) []float64 {
+		return acrobotDerivs(torque, s)
+	})
+	a.theta1, a.theta2 = wrapAngle(s[0]), wrapAngle(s[1])
+	a.dtheta1 = math.Max(-4*math.Pi, math.Min(4*math.Pi, s[2]))
+	a.dtheta2 = math.Max(-9*math.Pi, math.Min(9*math.Pi, s[3]))
+	done := -math.Cos(a.theta1)-math.Cos(a.theta1+a.theta2) > 1
+	if done {
+		return 0, true
+	}
+	return -1, false
+}
+
+// acrobotDerivs are the equations of motion from Sutton and Barto's book
+func acrobotDerivs(torque float64, s []float64) []float64 {
+	const (
+		m1, m2   = 1.0, 1.0
+		l1       = 1.0
+		lc1, lc2 = 0.5, 0.5
+		i1, i2   = 1.0, 1.0
+		g        = 9.8
+	)
+	theta1, theta2, dtheta1, dtheta2 := s[0], s[1], s[2], s[3]
+	d1 := m1*lc1*lc1 + m2*(l1*l1+lc2*lc2+2*l1*lc2*math.Cos(theta2)) + i1 + i2
+	d2 := m2*(lc2*lc2+l1*lc2*math.Cos(theta2)) + i2
+	phi2 := m2 * lc2 * g * math.Cos(theta1+theta2-math.Pi/2)
+	phi1 := -m2*l1*lc2*dtheta2*dtheta2*math.Sin(theta2) -
+		2*m2*l1*lc2*dtheta2*dtheta1*math.Sin(theta2) +
+		(m1*lc1+m2*l1)*g*math.Cos(theta1-math.Pi/2) + phi2
+	ddtheta2 := (torque + d2/d1*phi1 - m2*l1*lc2*dtheta1*dtheta1*math.Sin(theta2) - phi2) /
+		(m2*lc2*lc2 + i2 - d2*d2/d1)
+	ddtheta1 := -(d2*ddtheta2 + phi1) / d1
+	return []float64{dtheta1, dtheta2, ddtheta1, ddtheta2}
+}
+
+// wrapAngle maps an angle to [-pi, pi)
+func wrapAngle(a float64) float64 {
+	return math.Mod(math.Mod(a+math.Pi, 2*math.Pi)+2*math.Pi, 2*math.Pi) - math.Pi
+}
+
+// Pendulum swings up and balances a pendulum with a weak motor. The first
+// action value in [-1, 1] is the torque. The reward is minus the distance
+// from upright, the speed and the torque used, so it's never positive and the
+// episode never ends by itself.
+type Pendulum struct {
+	theta, dtheta float64
+}
+
+const (
+	pendulumMaxTorque = 2.0
+	pendulumMaxSpeed  = 8.0
+	pendulumTimeStep  = 0.05
+	pendulumGravity   = 10.0
+)
+
+// PendulumRunner is the reference fitness of the pendulum, 200 steps per
+// episode
+func PendulumRunner() *Runner {
+	r := NewRunner(func() Environment { return &Pendulum{} })
+	r.Episodes = 3
+	r.MaxSteps = 200
+	r.Action = ContinuousAction(-1, 1)
+	return r
+}
+
+func (p *Pendulum) Reset(rng *rand.Rand) {
+	p.theta = rng.Float64()*2*math.Pi - math.Pi
+	p.dtheta = rng.Float64()*2 - 1
+}
+
+func (p *Pendulum) Observe() []float64 {
+	return []float64{math.Cos(p.theta), math.Sin(p.theta), p.dtheta / pendulumMaxSpeed}
+}
+
+func (p *Pendulum) Step(action []float64) (float64, bool) {
+	u := 0.0
+	if len(action) > 0 {
+		u = math.Max(-1, math.Min(1, action[0])) * pendulumMaxTorque
+	}
+	theta := wrapAngle(p.theta)
+	cost := theta*theta + 0.1*p.dtheta*p.dtheta + 0.001*u*u
+
+	// mass and length are 1
+	p.dtheta += (3*pendulumGravity/2*math.Sin(p.theta) + 3*u) * pendulumTimeStep
+	p.dtheta = math.Max(-pendulumMaxSpeed, math.Min(pendulumMaxSpeed, p.dtheta))
+	p.theta += p.dtheta * pendulumTimeStep
+	return -cost, false
+}
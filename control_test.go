@@ -0,0 +1,125 @@
+package net
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runPolicy runs an episode of env with a hand written controller and returns
+// the return and the amount of steps
+func runPolicy(env Environment, steps int, policy func(obs []float64) []float64) (float64, int) {
+	env.Reset(rand.New(rand.NewSource(1)))
+	var ret float64
+	for i := 0; i < steps; i++ {
+		reward, done := env.Step(policy(env.Observe()))
+		ret += reward
+		if done {
+			return ret, i + 1
+		}
+	}
+	return ret, steps
+}
+
+func TestCartPole(t *testing.T) {
+	for _, poles := range []int{1, 2} {
+		c, err := NewCartPole(poles, true)
+		require.NoError(t, err)
+		c.Reset(rand.New(rand.NewSource(1)))
+		assert.Len(t, c.Observe(), c.InSize())
+		assert.Len(t, c.Observe(), 2+2*poles)
+		c2, err := NewCartPole(poles, false)
+		require.NoError(t, err)
+		assert.Len(t, c2.Observe(), 1+poles)
+
+		// without a push the poles fall
+		_, steps := runPolicy(c, 1000, func([]float64) []float64 { return []float64{0} })
+		assert.Less(t, steps, 1000)
+	}
+
+	for _, poles := range []int{-1, 0, 3} {
+		_, err := NewCartPole(poles, true)
+		assert.Error(t, err)
+		_, err = CartPoleRunner(poles, true)
+		assert.Error(t, err)
+	}
+
+	// pushing the cart under the pole balances it
+	c, err := NewCartPole(1, true)
+	require.NoError(t, err)
+	ret, steps := runPolicy(c, 1000, func(obs []float64) []float64 {
+		return []float64{obs[0] + obs[1] + 2*obs[2] + obs[3]}
+	})
+	assert.Equal(t, 1000, steps)
+	assert.Equal(t, 1000.0, ret)
+}
+
+func TestMountainCar(t *testing.T) {
+	// pushing along with the velocity builds momentum
+	_, steps := runPolicy(&MountainCar{}, 200, func(obs []float64) []float64 {
+		if obs[1] < 0 {
+			return []float64{1, 0, 0}
+		}
+		return []float64{0, 0, 1}
+	})
+	assert.Less(t, steps, 200)
+
+	_, steps = runPolicy(&MountainCar{}, 200, func([]float64) []float64 { return []float64{0, 1, 0} })
+	assert.Equal(t, 200, steps)
+}
+
+func TestAcrobot(t *testing.T) {
+	// torque along with the joint's velocity pumps energy in
+	_, steps := runPolicy(&Acrobot{}, 500, func(obs []float64) []float64 {
+		if obs[5] < 0 {
+			return []float64{1, 0, 0}
+		}
+		return []float64{0, 0, 1}
+	})
+	assert.Less(t, steps, 500)
+	assert.InDelta(t, -math.Pi, wrapAngle(math.Pi), 1e-12)
+	assert.InDelta(t, 0.5, wrapAngle(0.5+4*math.Pi), 1e-12)
+}
+
+func TestPendulum(t *testing.T) {
+	ret, steps := runPolicy(&Pendulum{}, 200, func([]float64) []float64 { return []float64{1} })
+	assert.Equal(t, 200, steps)
+	assert.Less(t, ret, 0.0)
+}
+
+func cartPoleRunner(t *testing.T, poles int, velocity bool) *Runner {
+	r, err := CartPoleRunner(poles, velocity)
+	require.NoError(t, err)
+	return r
+}
+
+func TestControlRunners(t *testing.T) {
+	runners := []struct {
+		r        *Runner
+		in, out  int
+		min, max float64
+	}{
+		{cartPoleRunner(t, 1, true), 4, 1, -1000, 0},
+		{cartPoleRunner(t, 2, false), 3, 1, -1000, 0},
+		{MountainCarRunner(), 2, 3, 0, 200},
+		{AcrobotRunner(), 6, 3, 0, 500},
+		{PendulumRunner(), 3, 1, 0, math.Inf(1)},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range runners {
+		r := func() float64 { return rng.Float64()*2 - 1 }
+		n, err := NewBuilder().Size(tt.in, 4, tt.out).WeightFunc(r).BiasFunc(r).Build()
+		require.NoError(t, err)
+		fit, err := tt.r.Fitness(n)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, fit, tt.min)
+		assert.LessOrEqual(t, fit, tt.max)
+		// deterministic
+		again, err := tt.r.Fitness(n)
+		require.NoError(t, err)
+		assert.Equal(t, fit, again)
+	}
+}
@@ -0,0 +1,69 @@
+package net
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCTRNN(t *testing.T) {
+	n, err := NewBuilder().
+		ActivationFunc(simple).
+		WeightFunc(fakeWeight).
+		HiddenKind(KindCTRNN).
+		Tau(1).
+		TimeStep(0.5).
+		Build()
+	require.NoError(t, err)
+	for _, neur := range n.neuronStore {
+		neur.bias = 1
+	}
+
+	// the hidden potentials move halfway towards their input of 2 every Eval
+	out, err := n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{5, 5}, out)
+
+	out, err = n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{6, 6}, out)
+
+	n.Reset()
+	out, err = n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{5, 5}, out)
+}
+
+func TestCTRNN_DNA(t *testing.T) {
+	n, err := NewBuilder().HiddenKind(KindCTRNN).Tau(2).Build()
+	require.NoError(t, err)
+
+	dna := NetToDna(n)
+	data, err := json.Marshal(dna)
+	require.NoError(t, err)
+	var dna2 DNA
+	require.NoError(t, json.Unmarshal(data, &dna2))
+	assert.Equal(t, dna.SynapseMap, dna2.SynapseMap)
+	assert.ElementsMatch(t, dna.Neurons, dna2.Neurons)
+
+	n2, err := DNAToNet(dna2)
+	require.NoError(t, err)
+	for _, neur := range n2.hidden {
+		assert.Equal(t, byte(KindCTRNN), neur.kind)
+		assert.Equal(t, 2.0, neur.tau)
+	}
+
+	dna2.Mutate(rand.New(rand.NewSource(1)))
+	for _, ng := range dna2.Neurons {
+		if ng.Kind == KindCTRNN {
+			assert.NotEqual(t, 2.0, ng.Tau)
+			assert.Greater(t, ng.Tau, 0.0)
+		}
+	}
+
+	_, err = DNAToDense(dna2)
+	assert.Error(t, err)
+}
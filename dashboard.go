@@ -0,0 +1,213 @@
+package net
+
+import (
+	"bytes"
+	_ "embed" // dashboard.html
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// Dashboard serves the progress of a run over http: fitness curves, the
+// population statistics of the last generation and the best net, updated live
+// with server-sent events. It's an http.Handler, mount it or use
+// ListenAndServe.
+//
+//	GET /          the dashboard page
+//	GET /history   every update so far as json
+//	GET /events    an update event per Update
+//	GET /net.svg   the best net
+type Dashboard struct {
+	// MaxHistory is the amount of updates kept for new clients, older ones
+	// are dropped. 0 keeps everything.
+	MaxHistory int
+
+	mu      sync.Mutex
+	history []DashboardUpdate
+	svg     []byte
+	clients map[chan []byte]struct{}
+	mux     *http.ServeMux
+}
+
+// DashboardUpdate is the state after a generation
+type DashboardUpdate struct {
+	Metrics GenerationMetrics
+	// Fitness is 0 when it isn't finite, which json can't encode, Finite
+	// tells
+	Fitness  float64
+	Finite   bool
+	Neurons  int
+	Synapses int
+	// SVG is only sent in events, the history leaves it out
+	SVG string `json:",omitempty"`
+}
+
+// NewDashboard returns a Dashboard keeping the last 10000 updates
+func NewDashboard() *Dashboard {
+	d := &Dashboard{
+		MaxHistory: 10000,
+		clients:    make(map[chan []byte]struct{}),
+		mux:        http.NewServeMux(),
+	}
+	d.mux.HandleFunc("/", d.serveIndex)
+	d.mux.HandleFunc("/history", d.serveHistory)
+	d.mux.HandleFunc("/events", d.serveEvents)
+	d.mux.HandleFunc("/net.svg", d.serveSVG)
+	return d
+}
+
+// Update publishes a generation and its best net with its fitness. Time and
+// EvalSeconds of m are filled in when they're zero.
+func (d *Dashboard) Update(m GenerationMetrics, best DNA, fitness float64) error {
+	if m.Time.IsZero() {
+		m.Time = time.Now().UTC()
+	}
+	d.mu.Lock()
+	if m.EvalSeconds == 0 && len(d.history) > 0 {
+		m.EvalSeconds = m.Time.Sub(d.history[len(d.history)-1].Metrics.Time).Seconds()
+	}
+	d.mu.Unlock()
+
+	var svg bytes.Buffer
+	if err := WriteSVG(&svg, best); err != nil {
+		return err
+	}
+	u := DashboardUpdate{
+		Metrics:  finiteMetrics(m),
+		Fitness:  fitness,
+		Finite:   finite(fitness),
+		Neurons:  len(best.Neurons),
+		Synapses: len(best.SynapseMap),
+		SVG:      svg.String(),
+	}
+	if !u.Finite {
+		u.Fitness = 0
+	}
+	event, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	u.SVG = ""
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.history = append(d.history, u)
+	if d.MaxHistory > 0 && len(d.history) > d.MaxHistory {
+		d.history = d.history[len(d.history)-d.MaxHistory:]
+	}
+	d.svg = svg.Bytes()
+	for c := range d.clients {
+		select {
+		case c <- event:
+		default:
+			// the client is too slow, it misses this update
+		}
+	}
+	return nil
+}
+
+// finiteMetrics returns m with the values json can't encode set to 0, like
+// NewPopulationMetrics does
+func finiteMetrics(m GenerationMetrics) GenerationMetrics {
+	clean := func(fs ...*float64) {
+		for _, f := range fs {
+			if !finite(*f) {
+				*f = 0
+			}
+		}
+	}
+	clean(&m.EvalSeconds)
+	m.Populations = append([]PopulationMetrics(nil), m.Populations...)
+	for i := range m.Populations {
+		p := &m.Populations[i]
+		clean(&p.Best, &p.Mean, &p.Std, &p.Neurons, &p.Synapses)
+	}
+	return m
+}
+
+// UpdateEvolution publishes the current generation of e, for
+// Evolution.Callback
+func (d *Dashboard) UpdateEvolution(e *Evolution) error {
+	return d.Update(EvolutionMetrics(e), e.Best.DNA, e.Best.Fitness)
+}
+
+// ServeHTTP serves the dashboard
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the dashboard on addr, e.g. ":8080"
+func (d *Dashboard) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, d)
+}
+
+func (d *Dashboard) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+func (d *Dashboard) serveHistory(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	b, err := json.Marshal(d.history)
+	d.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (d *Dashboard) serveSVG(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	svg := d.svg
+	d.mu.Unlock()
+	if svg == nil {
+		http.Error(w, "no net yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(svg)
+}
+
+func (d *Dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events := make(chan []byte, 16)
+	d.mu.Lock()
+	d.clients[events] = struct{}{}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, events)
+		d.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if _, err := fmt.Fprintf(w, "event: update\ndata: %s\n\n", event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSVG(t *testing.T) {
+	n, err := NewBuilder().Size(2, 3, 1).Build()
+	require.NoError(t, err)
+	dna := NetToDna(n)
+	// a recurrent synapse, a loop and a neuron only a synapse refers to
+	dna.SynapseMap[SynapseGene{SourceID: 5, DestID: 2, Weight: -1}] = struct{}{}
+	dna.SynapseMap[SynapseGene{SourceID: 3, DestID: 3, Weight: 0.5}] = struct{}{}
+	dna.SynapseMap[SynapseGene{SourceID: 0, DestID: 42, Weight: 0.5}] = struct{}{}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSVG(&buf, dna))
+	dec := xml.NewDecoder(&buf)
+	var neurons, synapses int
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if el, ok := tok.(xml.StartElement); ok {
+			for _, a := range el.Attr {
+				if a.Name.Local == "class" && a.Value == "neuron" {
+					neurons++
+				}
+				if a.Name.Local == "class" && a.Value == "synapse" {
+					synapses++
+				}
+			}
+		}
+	}
+	assert.Equal(t, len(dna.Neurons)+1, neurons)
+	assert.Equal(t, len(dna.SynapseMap), synapses)
+
+	require.NoError(t, WriteSVG(&buf, DNA{}))
+}
+
+func TestDashboard(t *testing.T) {
+	d := NewDashboard()
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/net.svg")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/events", nil)
+	require.NoError(t, err)
+	events, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer events.Body.Close()
+	assert.Equal(t, "text/event-stream", events.Header.Get("Content-Type"))
+
+	n, err := NewBuilder().Size(2, 2, 1).Build()
+	require.NoError(t, err)
+	m := GenerationMetrics{Generation: 3, Populations: []PopulationMetrics{{Best: 1}}}
+	require.NoError(t, d.Update(m, NetToDna(n), 1))
+
+	r := bufio.NewReader(events.Body)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: update\n", line)
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	var u DashboardUpdate
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &u))
+	assert.Equal(t, 3, u.Metrics.Generation)
+	assert.Contains(t, u.SVG, "<svg")
+
+	res, err = http.Get(srv.URL + "/history")
+	require.NoError(t, err)
+	var history []DashboardUpdate
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&history))
+	res.Body.Close()
+	require.Len(t, history, 1)
+	assert.Equal(t, 1.0, history[0].Fitness)
+	assert.True(t, history[0].Finite)
+	assert.Empty(t, history[0].SVG)
+
+	// values json can't encode don't stop the updates
+	line = ""
+	m.Populations[0].Best = math.Inf(1)
+	require.NoError(t, d.Update(m, NetToDna(n), math.Inf(1)))
+	for !strings.HasPrefix(line, "data: ") {
+		line, err = r.ReadString('\n')
+		require.NoError(t, err)
+	}
+	u = DashboardUpdate{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &u))
+	assert.False(t, u.Finite)
+	assert.Equal(t, 0.0, u.Fitness)
+	assert.Equal(t, 0.0, u.Metrics.Populations[0].Best)
+
+	for _, path := range []string{"/", "/net.svg"} {
+		res, err = http.Get(srv.URL + path)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode, path)
+	}
+}
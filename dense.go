@@ -0,0 +1,304 @@
+package net
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Dense holds a fully connected layered network as one weight matrix per
+// layer. It evaluates like the equivalent Net without walking the neuron
+// graph, which makes it a lot faster for large layered nets.
+type Dense struct {
+	layers         []*denseLayer
+	activationFunc func(float64) float64
+	float32        bool
+}
+
+type denseLayer struct {
+	ids    []int
+	biases []float64
+	// weights is row major, one row per neuron of this layer holding the
+	// weights of the synapses coming from the previous layer
+	weights []float64
+	out     []float64
+
+	biases32  []float32
+	weights32 []float32
+	out32     []float32
+}
+
+func (d *Dense) InSize() int {
+	return len(d.layers[0].ids)
+}
+
+func (d *Dense) OutSize() int {
+	return len(d.layers[len(d.layers)-1].ids)
+}
+
+func newDense(b *Builder) *Dense {
+	d := &Dense{activationFunc: b.activationFunc}
+	sizes := []int{b.inSize, b.hiddenSize, b.outSize}
+	var id int
+	for l, size := range sizes {
+		layer := &denseLayer{
+			ids:    make([]int, size),
+			biases: make([]float64, size),
+			out:    make([]float64, size),
+		}
+		for i := range layer.ids {
+			layer.ids[i] = id
+			layer.biases[i] = b.biasFunc()
+			id++
+		}
+		if l > 0 {
+			layer.weights = make([]float64, size*sizes[l-1])
+			for i := range layer.weights {
+				layer.weights[i] = b.weightFunc()
+			}
+		}
+		d.layers = append(d.layers, layer)
+	}
+	d.UseFloat32(b.float32)
+	return d
+}
+
+// UseFloat32 switches evaluation to float32 arithmetic. The float64 weights
+// are kept so converting back to DNA stays lossless.
+func (d *Dense) UseFloat32(on bool) {
+	d.float32 = on
+	for _, layer := range d.layers {
+		if !on {
+			layer.biases32, layer.weights32, layer.out32 = nil, nil, nil
+			continue
+		}
+		layer.biases32 = make([]float32, len(layer.biases))
+		for i, b := range layer.biases {
+			layer.biases32[i] = float32(b)
+		}
+		layer.weights32 = make([]float32, len(layer.weights))
+		for i, w := range layer.weights {
+			layer.weights32[i] = float32(w)
+		}
+		layer.out32 = make([]float32, len(layer.ids))
+	}
+}
+
+// Eval sends the input through the network and returns the output
+func (d *Dense) Eval(input []float64) (output []float64, err error) {
+	if d == nil {
+		return nil, fmt.Errorf("Dense not initialised, use the Builder")
+	}
+	if len(input) != d.InSize() {
+		return nil, fmt.Errorf("input size %d, expected %d", len(input), d.InSize())
+	}
+	act := d.activationFunc
+	if act == nil {
+		act = sigmoid
+	}
+	if d.float32 {
+		return d.eval32(input, act), nil
+	}
+
+	prev := input
+	for _, layer := range d.layers[1:] {
+		for j := range layer.ids {
+			row := layer.weights[j*len(prev) : (j+1)*len(prev)]
+			var sum float64
+			for i, x := range prev {
+				sum += x * row[i]
+			}
+			bias := layer.biases[j]
+			layer.out[j] = act((sum + bias) * bias)
+		}
+		prev = layer.out
+	}
+	output = make([]float64, len(prev))
+	copy(output, prev)
+	return output, nil
+}
+
+func (d *Dense) eval32(input []float64, act func(float64) float64) []float64 {
+	prev := d.layers[0].out32
+	for i, x := range input {
+		prev[i] = float32(x)
+	}
+	for _, layer := range d.layers[1:] {
+		for j := range layer.ids {
+			row := layer.weights32[j*len(prev) : (j+1)*len(prev)]
+			var sum float32
+			for i, x := range prev {
+				sum += x * row[i]
+			}
+			bias := layer.biases32[j]
+			layer.out32[j] = float32(act(float64((sum + bias) * bias)))
+		}
+		prev = layer.out32
+	}
+	output := make([]float64, len(prev))
+	for i, x := range prev {
+		output[i] = float64(x)
+	}
+	return output
+}
+
+// DenseToDna encodes the dense network to dna
+func DenseToDna(d *Dense) (dna DNA) {
+	dna.SynapseMap = make(map[SynapseGene]struct{})
+	for l, layer := range d.layers {
+		kind := hiddenLayer
+		if l == 0 {
+			kind = inputLayer
+		} else if l == len(d.layers)-1 {
+			kind = outputLayer
+		}
+		for j, id := range layer.ids {
+			dna.Neurons = append(dna.Neurons, &NeuronGene{
+				ID:    id,
+				Bias:  layer.biases[j],
+				Layer: kind,
+			})
+			if l == 0 {
+				continue
+			}
+			prev := d.layers[l-1].ids
+			for i, source := range prev {
+				dna.SynapseMap[SynapseGene{
+					SourceID: source,
+					DestID:   id,
+					Weight:   layer.weights[j*len(prev)+i],
+				}] = struct{}{}
+			}
+		}
+	}
+	return dna
+}
+
+// DNAToDense decodes dna into a dense network. It fails when the dna does not
+// describe a fully connected layered network, use DNAToNet for those.
+func DNAToDense(dna DNA) (*Dense, error) {
+	genes := make(map[int]*NeuronGene, len(dna.Neurons))
+	for _, ng := range dna.Neurons {
+		if ng.Kind != KindStandard || ng.Activation != ActSigmoid {
+			return nil, fmt.Errorf("neuron %d is not a standard sigmoid neuron", ng.ID)
+		}
+		genes[ng.ID] = ng
+	}
+
+	sources := make(map[int][]SynapseGene)
+	for syn := range dna.SynapseMap {
+		if genes[syn.SourceID] == nil || genes[syn.DestID] == nil {
+			return nil, fmt.Errorf("synapse %d -> %d connects an unknown neuron", syn.SourceID, syn.DestID)
+		}
+		sources[syn.DestID] = append(sources[syn.DestID], syn)
+	}
+
+	// give every neuron the depth of its longest path from the input
+	depths := make(map[int]int, len(genes))
+	var depth func(id int, visiting map[int]bool) (int, error)
+	depth = func(id int, visiting map[int]bool) (int, error) {
+		if d, ok := depths[id]; ok {
+			return d, nil
+		}
+		if genes[id].Layer == inputLayer {
+			if len(sources[id]) != 0 {
+				return 0, fmt.Errorf("input neuron %d has incoming synapses", id)
+			}
+			depths[id] = 0
+			return 0, nil
+		}
+		if len(sources[id]) == 0 {
+			return 0, fmt.Errorf("neuron %d has no incoming synapses", id)
+		}
+		if visiting[id] {
+			return 0, fmt.Errorf("loop through neuron %d", id)
+		}
+		visiting[id] = true
+		var max int
+		for _, syn := range sources[id] {
+			d, err := depth(syn.SourceID, visiting)
+			if err != nil {
+				return 0, err
+			}
+			if d+1 > max {
+				max = d + 1
+			}
+		}
+		visiting[id] = false
+		depths[id] = max
+		return max, nil
+	}
+
+	var ids []int
+	for id := range genes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	layers := make(map[int][]int)
+	var outDepth int
+	for _, id := range ids {
+		d, err := depth(id, make(map[int]bool))
+		if err != nil {
+			return nil, err
+		}
+		if genes[id].Layer == outputLayer {
+			if outDepth != 0 && d != outDepth {
+				return nil, fmt.Errorf("output neurons are at different depths")
+			}
+			outDepth = d
+		}
+		layers[d] = append(layers[d], id)
+	}
+	if outDepth == 0 || len(layers) != outDepth+1 {
+		return nil, fmt.Errorf("dna is not a layered network")
+	}
+	for _, id := range layers[outDepth] {
+		if genes[id].Layer != outputLayer {
+			return nil, fmt.Errorf("hidden neuron %d does not reach the output", id)
+		}
+	}
+
+	d := new(Dense)
+	for l := 0; l <= outDepth; l++ {
+		layer := &denseLayer{
+			ids:    layers[l],
+			biases: make([]float64, len(layers[l])),
+			out:    make([]float64, len(layers[l])),
+		}
+		for j, id := range layer.ids {
+			layer.biases[j] = genes[id].Bias
+		}
+		if l > 0 {
+			if err := layer.connect(layers[l-1], sources); err != nil {
+				return nil, err
+			}
+		}
+		d.layers = append(d.layers, layer)
+	}
+	return d, nil
+}
+
+// connect fills the layer's weight matrix, every neuron needs exactly one
+// synapse from every neuron of the previous layer
+func (layer *denseLayer) connect(prev []int, sources map[int][]SynapseGene) error {
+	col := make(map[int]int, len(prev))
+	for i, id := range prev {
+		col[id] = i
+	}
+	layer.weights = make([]float64, len(layer.ids)*len(prev))
+	for j, id := range layer.ids {
+		if len(sources[id]) != len(prev) {
+			return fmt.Errorf("neuron %d is not fully connected to the previous layer", id)
+		}
+		seen := make(map[int]bool, len(prev))
+		for _, syn := range sources[id] {
+			i, ok := col[syn.SourceID]
+			if !ok || seen[i] {
+				return fmt.Errorf("neuron %d is not fully connected to the previous layer", id)
+			}
+			seen[i] = true
+			layer.weights[j*len(prev)+i] = syn.Weight
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package net
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNAToDense(t *testing.T) {
+	inSize, hiddenSize, outSize := 3, 10, 4
+	n, err := NewBuilder().Size(inSize, hiddenSize, outSize).Build()
+	require.NoError(t, err)
+
+	d, err := DNAToDense(NetToDna(n))
+	require.NoError(t, err)
+	assert.Equal(t, inSize, d.InSize())
+	assert.Equal(t, outSize, d.OutSize())
+
+	input := []float64{1, -0.5, 0.25}
+	out, err := n.Eval(input)
+	require.NoError(t, err)
+	out2, err := d.Eval(input)
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, out, out2, 1e-12)
+
+	d.UseFloat32(true)
+	out3, err := d.Eval(input)
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, out, out3, 1e-5)
+}
+
+func TestDenseToDna(t *testing.T) {
+	d, err := NewBuilder().Size(2, 5, 3).Float32().BuildDense()
+	require.NoError(t, err)
+
+	dna := DenseToDna(d)
+	assert.Len(t, dna.Neurons, 2+5+3)
+	assert.Len(t, dna.SynapseMap, 2*5+5*3)
+
+	d2, err := DNAToDense(dna)
+	require.NoError(t, err)
+	assert.Equal(t, dna.SynapseMap, DenseToDna(d2).SynapseMap)
+
+	n, err := DNAToNet(dna)
+	require.NoError(t, err)
+	out, err := n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	out2, err := d2.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, out, out2, 1e-12)
+}
+
+func TestDNAToDense_not_layered(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 2).Build()
+	require.NoError(t, err)
+
+	dna := NetToDna(n)
+	dna.SynapseMap[SynapseGene{SourceID: 0, DestID: 4, Weight: 1}] = struct{}{}
+	_, err = DNAToDense(dna)
+	assert.Error(t, err)
+
+	dna = NetToDna(n)
+	dna.SynapseMap[SynapseGene{SourceID: 4, DestID: 2, Weight: 1}] = struct{}{}
+	_, err = DNAToDense(dna)
+	assert.Error(t, err)
+}
+
+func BenchmarkDenseEvalLarge(b *testing.B) {
+	inSize, hiddenSize, outSize := 100, 100, 100
+	d, err := NewBuilder().Size(inSize, hiddenSize, outSize).BuildDense()
+	if err != nil {
+		b.Error(err)
+	}
+	var input []float64
+	for i := 0; i < inSize; i++ {
+		input = append(input, 1)
+	}
+	for i := 0; i < b.N; i++ {
+		d.Eval(input)
+	}
+}
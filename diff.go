@@ -0,0 +1,281 @@
+package net
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Change is how a neuron or synapse differs between two genomes
+type Change int
+
+// Changes of a NeuronDiff or SynapseDiff
+const (
+	Unchanged Change = iota
+	Added
+	Removed
+	Changed
+)
+
+func (c Change) String() string {
+	return [...]string{"unchanged", "added", "removed", "changed"}[c]
+}
+
+// NeuronDiff compares a neuron, matched by id. Old is nil for an added neuron
+// and New for a removed one.
+type NeuronDiff struct {
+	ID       int
+	Change   Change
+	Old, New *NeuronGene
+}
+
+// BiasDelta is the change of the bias, 0 unless the neuron is in both
+func (d NeuronDiff) BiasDelta() float64 {
+	if d.Old == nil || d.New == nil {
+		return 0
+	}
+	return d.New.Bias - d.Old.Bias
+}
+
+// SynapseDiff compares the synapses from SourceID to DestID. Several
+// synapses between the same neurons add up, so they're compared on the sum of
+// their weights.
+type SynapseDiff struct {
+	SourceID, DestID     int
+	Change               Change
+	OldWeight, NewWeight float64
+}
+
+// WeightDelta is the change of the weight, 0 unless the synapse is in both
+func (d SynapseDiff) WeightDelta() float64 {
+	if d.Change == Added || d.Change == Removed {
+		return 0
+	}
+	return d.NewWeight - d.OldWeight
+}
+
+// DNADiff is the difference between two genomes, with an entry for every
+// neuron and synapse in either, sorted by id
+type DNADiff struct {
+	Neurons  []NeuronDiff
+	Synapses []SynapseDiff
+}
+
+// Diff compares before to after, matching neurons by id and synapses by
+// source and destination
+func Diff(before, after DNA) DNADiff {
+	var d DNADiff
+	oldNeurons, newNeurons := neuronsByID(before), neuronsByID(after)
+	for _, id := range unionIDs(oldNeurons, newNeurons) {
+		nd := NeuronDiff{ID: id, Old: oldNeurons[id], New: newNeurons[id]}
+		switch {
+		case nd.Old == nil:
+			nd.Change = Added
+		case nd.New == nil:
+			nd.Change = Removed
+		case len(neuronChanges(nd.Old, nd.New)) > 0:
+			nd.Change = Changed
+		}
+		d.Neurons = append(d.Neurons, nd)
+	}
+
+	oldSyns, newSyns := synapseSums(before), synapseSums(after)
+	var keys [][2]int
+	for k := range oldSyns {
+		keys = append(keys, k)
+	}
+	for k := range newSyns {
+		if _, ok := oldSyns[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		oldW, inOld := oldSyns[k]
+		newW, inNew := newSyns[k]
+		sd := SynapseDiff{SourceID: k[0], DestID: k[1], OldWeight: oldW, NewWeight: newW}
+		switch {
+		case !inOld:
+			sd.Change = Added
+		case !inNew:
+			sd.Change = Removed
+		case oldW != newW:
+			sd.Change = Changed
+		}
+		d.Synapses = append(d.Synapses, sd)
+	}
+	return d
+}
+
+func neuronsByID(dna DNA) map[int]*NeuronGene {
+	m := make(map[int]*NeuronGene, len(dna.Neurons))
+	for _, ng := range dna.Neurons {
+		m[ng.ID] = ng
+	}
+	return m
+}
+
+func unionIDs(a, b map[int]*NeuronGene) []int {
+	ids := make([]int, 0, len(a)+len(b))
+	for id := range a {
+		ids = append(ids, id)
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func synapseSums(dna DNA) map[[2]int]float64 {
+	m := make(map[[2]int]float64, len(dna.SynapseMap))
+	for _, syn := range sortedSynapses(dna.SynapseMap) {
+		m[[2]int{syn.SourceID, syn.DestID}] += syn.Weight
+	}
+	return m
+}
+
+// neuronChanges describes what differs between two versions of a neuron
+func neuronChanges(a, b *NeuronGene) []string {
+	var changes []string
+	if a.Bias != b.Bias {
+		changes = append(changes, fmt.Sprintf("bias %.4g -> %.4g (%+.4g)", a.Bias, b.Bias, b.Bias-a.Bias))
+	}
+	if a.Layer != b.Layer {
+		changes = append(changes, fmt.Sprintf("layer %d -> %d", a.Layer, b.Layer))
+	}
+	if a.Kind != b.Kind {
+		changes = append(changes, fmt.Sprintf("kind %s -> %s", KindName(a.Kind), KindName(b.Kind)))
+	}
+	if a.Activation != b.Activation {
+		changes = append(changes, fmt.Sprintf("activation %d -> %d", a.Activation, b.Activation))
+	}
+	if a.Tau != b.Tau {
+		changes = append(changes, fmt.Sprintf("tau %.4g -> %.4g", a.Tau, b.Tau))
+	}
+	if len(a.Gates) != len(b.Gates) {
+		changes = append(changes, fmt.Sprintf("%d -> %d gate parameters", len(a.Gates), len(b.Gates)))
+	} else {
+		for i := range a.Gates {
+			if a.Gates[i] != b.Gates[i] {
+				changes = append(changes, "gate parameters")
+				break
+			}
+		}
+	}
+	return changes
+}
+
+// Counts returns the amount of added, removed and changed neurons and
+// synapses
+func (d DNADiff) Counts() (neurons, synapses [4]int) {
+	for _, nd := range d.Neurons {
+		neurons[nd.Change]++
+	}
+	for _, sd := range d.Synapses {
+		synapses[sd.Change]++
+	}
+	return neurons, synapses
+}
+
+// Empty is true if both genomes are the same
+func (d DNADiff) Empty() bool {
+	n, s := d.Counts()
+	return n[Unchanged] == len(d.Neurons) && s[Unchanged] == len(d.Synapses)
+}
+
+// WriteText writes a line for every neuron and synapse that changed, prefixed
+// with + for added, - for removed and ~ for changed, and a summary
+func (d DNADiff) WriteText(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	for _, nd := range d.Neurons {
+		switch nd.Change {
+		case Added:
+			fmt.Fprintf(b, "+ neuron %d %s bias %.4g\n", nd.ID, KindName(nd.New.Kind), nd.New.Bias)
+		case Removed:
+			fmt.Fprintf(b, "- neuron %d %s bias %.4g\n", nd.ID, KindName(nd.Old.Kind), nd.Old.Bias)
+		case Changed:
+			fmt.Fprintf(b, "~ neuron %d %s\n", nd.ID, strings.Join(neuronChanges(nd.Old, nd.New), ", "))
+		}
+	}
+	for _, sd := range d.Synapses {
+		switch sd.Change {
+		case Added:
+			fmt.Fprintf(b, "+ synapse %d -> %d weight %.4g\n", sd.SourceID, sd.DestID, sd.NewWeight)
+		case Removed:
+			fmt.Fprintf(b, "- synapse %d -> %d weight %.4g\n", sd.SourceID, sd.DestID, sd.OldWeight)
+		case Changed:
+			fmt.Fprintf(b, "~ synapse %d -> %d weight %.4g -> %.4g (%+.4g)\n",
+				sd.SourceID, sd.DestID, sd.OldWeight, sd.NewWeight, sd.WeightDelta())
+		}
+	}
+	n, s := d.Counts()
+	fmt.Fprintf(b, "neurons: %d added, %d removed, %d changed, %d unchanged\n", n[Added], n[Removed], n[Changed], n[Unchanged])
+	fmt.Fprintf(b, "synapses: %d added, %d removed, %d changed, %d unchanged\n", s[Added], s[Removed], s[Changed], s[Unchanged])
+	return b.Flush()
+}
+
+// diff colours of the dot graph, by Change
+var diffColors = [...]string{"gray60", "green4", "red3", "orange2"}
+
+// WriteDot writes both genomes as one graphviz graph. Added parts are green,
+// removed ones red, changed ones orange and the rest gray. Changed synapses
+// are labelled with their weight delta, changed neurons with their bias delta.
+func (d DNADiff) WriteDot(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph G {")
+	fmt.Fprintln(b, "\trankdir=LR;")
+	fmt.Fprintln(b, "\tnode [shape=circle];")
+
+	layers := make([][]NeuronDiff, 3)
+	for _, nd := range d.Neurons {
+		ng := nd.New
+		if ng == nil {
+			ng = nd.Old
+		}
+		l := ng.Layer
+		if l < inputLayer || l > outputLayer {
+			l = hiddenLayer
+		}
+		layers[l] = append(layers[l], nd)
+	}
+	for l, name := range []string{"input", "hidden", "output"} {
+		fmt.Fprintf(b, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n\t\tcolor=white;\n", l, name)
+		for _, nd := range layers[l] {
+			label := fmt.Sprint(nd.ID)
+			if nd.Change == Changed && nd.BiasDelta() != 0 {
+				label += fmt.Sprintf("\\n%+.3g", nd.BiasDelta())
+			}
+			style := ""
+			if nd.Change == Removed {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(b, "\t\t%d [label=\"%s\", color=%s, fontcolor=%s%s];\n",
+				nd.ID, label, diffColors[nd.Change], diffColors[nd.Change], style)
+		}
+		fmt.Fprintln(b, "\t}")
+	}
+	for _, sd := range d.Synapses {
+		attrs := fmt.Sprintf("color=%s", diffColors[sd.Change])
+		switch sd.Change {
+		case Changed:
+			attrs += fmt.Sprintf(", label=\"%+.3g\", fontcolor=%s", sd.WeightDelta(), diffColors[sd.Change])
+		case Removed:
+			attrs += ", style=dashed"
+		case Added:
+			attrs += ", penwidth=2"
+		}
+		fmt.Fprintf(b, "\t%d -> %d [%s];\n", sd.SourceID, sd.DestID, attrs)
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
@@ -0,0 +1,67 @@
+package net
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	n, err := NewBuilder().Size(2, 1, 1).Build()
+	require.NoError(t, err)
+	before := NetToDna(n)
+	assert.True(t, Diff(before, before).Empty())
+
+	after := before.Clone()
+	after.Neurons[2].Bias += 0.5
+	after.Neurons = append(after.Neurons, &NeuronGene{ID: 9, Layer: hiddenLayer, Kind: KindCTRNN})
+	after.SynapseMap[SynapseGene{SourceID: 0, DestID: 9, Weight: 2}] = struct{}{}
+	for syn := range after.SynapseMap {
+		if syn.SourceID == 1 {
+			delete(after.SynapseMap, syn)
+			break
+		}
+	}
+	// a second synapse between the same neurons adds to the weight
+	var changed SynapseGene
+	for syn := range after.SynapseMap {
+		if syn.SourceID == 0 && syn.DestID != 9 {
+			changed = syn
+			break
+		}
+	}
+	after.SynapseMap[SynapseGene{SourceID: changed.SourceID, DestID: changed.DestID, Weight: 0.25}] = struct{}{}
+
+	d := Diff(before, after)
+	assert.False(t, d.Empty())
+	neurons, synapses := d.Counts()
+	assert.Equal(t, [4]int{len(before.Neurons) - 1, 1, 0, 1}, neurons)
+	assert.Equal(t, 1, synapses[Added])
+	assert.Equal(t, 1, synapses[Removed])
+	assert.Equal(t, 1, synapses[Changed])
+	for _, nd := range d.Neurons {
+		if nd.Change == Changed {
+			assert.Equal(t, after.Neurons[2].ID, nd.ID)
+			assert.Equal(t, 0.5, nd.BiasDelta())
+		}
+	}
+	for _, sd := range d.Synapses {
+		if sd.Change == Changed {
+			assert.Equal(t, [2]int{changed.SourceID, changed.DestID}, [2]int{sd.SourceID, sd.DestID})
+			assert.InDelta(t, 0.25, sd.WeightDelta(), 1e-12)
+		}
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, d.WriteText(&text))
+	assert.Contains(t, text.String(), "+ neuron 9 ctrnn")
+	assert.Contains(t, text.String(), "+ synapse 0 -> 9 weight 2")
+	assert.Contains(t, text.String(), "neurons: 1 added, 0 removed, 1 changed")
+
+	var dot bytes.Buffer
+	require.NoError(t, d.WriteDot(&dot))
+	assert.Contains(t, dot.String(), "0 -> 9 [color=green4, penwidth=2]")
+	assert.Contains(t, dot.String(), "style=dashed")
+}
@@ -1,6 +1,8 @@
 package net
 
 import (
+	"fmt"
+	"math"
 	"math/rand"
 	"sort"
 
@@ -14,9 +16,13 @@ type DNA struct {
 }
 
 type NeuronGene struct {
-	ID    int
-	Bias  float64
-	Layer int
+	ID         int
+	Bias       float64
+	Layer      int
+	Kind       int       `json:",omitempty"`
+	Tau        float64   `json:",omitempty"`
+	Gates      []float64 `json:",omitempty"` // parameters of GRU and LSTM neurons
+	Activation int       `json:",omitempty"`
 }
 
 type SynapseGene struct {
@@ -31,6 +37,7 @@ func NetToDna(n *Net) (dna DNA) {
 	for _, neur := range n.neuronStore {
 		dna.Neurons = append(dna.Neurons, neur.DNA())
 	}
+	sort.Slice(dna.Neurons, func(i, j int) bool { return dna.Neurons[i].ID < dna.Neurons[j].ID })
 	for s := range n.synapses() {
 		dna.SynapseMap[*s.DNA()] = struct{}{}
 	}
@@ -43,10 +50,24 @@ func DNAToNet(dna DNA) (*Net, error) {
 
 	{ // Create neurons and add to layers
 		for _, neurGene := range dna.Neurons {
+			act, ok := activationFuncs[neurGene.Activation]
+			if !ok {
+				return nil, fmt.Errorf("neuron %d has unknown activation %d", neurGene.ID, neurGene.Activation)
+			}
+			if len(neurGene.Gates) != gateCount(neurGene.Kind) {
+				return nil, fmt.Errorf("neuron %d has %d gate parameters, expected %d",
+					neurGene.ID, len(neurGene.Gates), gateCount(neurGene.Kind))
+			}
 			neur := &neuron{
-				id:    neurGene.ID,
-				layer: byte(neurGene.Layer),
-				bias:  neurGene.Bias,
+				id:             neurGene.ID,
+				layer:          byte(neurGene.Layer),
+				bias:           neurGene.Bias,
+				kind:           byte(neurGene.Kind),
+				tau:            neurGene.Tau,
+				gates:          append([]float64(nil), neurGene.Gates...),
+				net:            n,
+				activation:     byte(neurGene.Activation),
+				activationFunc: act,
 			}
 			n.neuronStore[neurGene.ID] = neur
 			switch neur.layer {
@@ -66,8 +87,8 @@ func DNAToNet(dna DNA) (*Net, error) {
 		sort.Slice(n.hidden, func(i, j int) bool { return n.hidden[i].id < n.hidden[j].id })
 	}
 
-	{ // add synapes to neurons
-		for synGene := range dna.SynapseMap {
+	{ // add synapes to neurons, sorted so evaluation order doesn't change between calls
+		for _, synGene := range sortedSynapses(dna.SynapseMap) {
 			var source *neuron
 			var dest *neuron
 			var ok bool
@@ -78,6 +99,7 @@ func DNAToNet(dna DNA) (*Net, error) {
 					id:    synGene.SourceID,
 					layer: hiddenLayer,
 					bias:  defaultBiasFunc(),
+					net:   n,
 				}
 				n.neuronStore[source.id] = source
 			}
@@ -88,6 +110,7 @@ func DNAToNet(dna DNA) (*Net, error) {
 					id:    synGene.DestID,
 					layer: hiddenLayer,
 					bias:  defaultBiasFunc(),
+					net:   n,
 				}
 				n.neuronStore[dest.id] = dest
 
@@ -147,6 +170,9 @@ func (g Syns) Copy() eaopt.Slice {
 	return t
 }
 
+// Mutate changes the biases and gate parameters in place and adds, and now and
+// then removes, a synapse. The weight changes and rewiring it computes are
+// lost, MutateAll keeps them.
 func (dna DNA) Mutate(rng *rand.Rand) {
 	var weights []float64
 	var sources []int
@@ -188,6 +214,11 @@ func (dna DNA) Mutate(rng *rand.Rand) {
 
 	for i, neur := range dna.Neurons {
 		neur.Bias = biases[i]
+		if neur.Kind == KindCTRNN {
+			// log-normal keeps the time constant positive
+			neur.Tau *= math.Exp(rng.NormFloat64() * 0.2)
+		}
+		eaopt.MutNormalFloat64(neur.Gates, 1, rng)
 	}
 
 	// Add synapses
@@ -212,6 +243,84 @@ func (dna DNA) Mutate(rng *rand.Rand) {
 	}
 }
 
+// MutateAll changes the dna in place like Mutate, but keeps the weight
+// changes and rewired synapses Mutate loses, and adds the neurons a new
+// synapse needs. All randomness comes from rng, so the same seed gives the
+// same mutations. It's the default mutation of Evolution, NSGA2 and
+// MapElites.
+func (dna *DNA) MutateAll(rng *rand.Rand) {
+	var weights []float64
+	var sources []int
+	var destinations []int
+	var synapses []*SynapseGene
+	for _, syn := range sortedSynapses(dna.SynapseMap) {
+		synapses = append(synapses, &SynapseGene{
+			SourceID: syn.SourceID,
+			DestID:   syn.DestID,
+			Weight:   syn.Weight,
+		})
+	}
+
+	for _, syn := range synapses {
+		weights = append(weights, syn.Weight)
+		sources = append(sources, syn.SourceID)
+		destinations = append(destinations, syn.DestID)
+	}
+
+	var biases []float64
+	for _, neur := range dna.Neurons {
+		biases = append(biases, neur.Bias)
+	}
+
+	eaopt.MutNormalFloat64(weights, 1, rng)
+	eaopt.MutNormalFloat64(biases, 1, rng)
+
+	// Switch connections
+	if rng.Float64() < 1 {
+		eaopt.MutPermuteInt(sources, 1, rng)
+		eaopt.MutPermuteInt(destinations, 1, rng)
+	}
+
+	for key := range dna.SynapseMap {
+		delete(dna.SynapseMap, key)
+	}
+	for i, syn := range synapses {
+		syn.Weight = weights[i]
+		syn.SourceID = sources[i]
+		syn.DestID = destinations[i]
+		dna.SynapseMap[*syn] = struct{}{}
+	}
+
+	for i, neur := range dna.Neurons {
+		neur.Bias = biases[i]
+		if neur.Kind == KindCTRNN {
+			// log-normal keeps the time constant positive
+			neur.Tau *= math.Exp(rng.NormFloat64() * 0.2)
+		}
+		eaopt.MutNormalFloat64(neur.Gates, 1, rng)
+	}
+
+	// Add synapses
+	if rng.Float64() < 1 {
+		sourceID := rng.Intn(len(dna.Neurons) + 3)
+		destID := rng.Intn(len(dna.Neurons) + 3)
+
+		sg := SynapseGene{
+			SourceID: sourceID,
+			DestID:   destID,
+			Weight:   rng.Float64(),
+		}
+		dna.SynapseMap[sg] = struct{}{}
+		dna.addMissingNeurons(rng, sourceID, destID)
+	}
+
+	// remove synapses
+	if rng.Float64() < 0.01 && len(dna.SynapseMap) > 2 {
+		syns := sortedSynapses(dna.SynapseMap)
+		delete(dna.SynapseMap, syns[rng.Intn(len(syns))])
+	}
+}
+
 func (dna DNA) Crossover(dna2 DNA, rng *rand.Rand) {
 	if len(dna2.SynapseMap) != len(dna.SynapseMap) {
 		return
@@ -269,6 +378,59 @@ func (dna DNA) Crossover(dna2 DNA, rng *rand.Rand) {
 	}
 }
 
+// addMissingNeurons adds hidden neurons for ids the dna doesn't have yet, so
+// their bias comes from rng instead of from DNAToNet
+func (dna *DNA) addMissingNeurons(rng *rand.Rand, ids ...int) {
+	for _, id := range ids {
+		var found bool
+		for _, ng := range dna.Neurons {
+			if ng.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dna.Neurons = append(dna.Neurons, &NeuronGene{
+				ID:    id,
+				Bias:  rng.Float64()*2 - 1,
+				Layer: hiddenLayer,
+			})
+		}
+	}
+}
+
+// Mate returns a child of dna and dna2. Synapses are aligned by source and
+// destination, those both parents have are taken from either at random, the
+// others from dna. Neurons are taken from dna, with the bias of a neuron both
+// parents have taken from either at random. Unlike Crossover this works for
+// parents of different sizes.
+func (dna DNA) Mate(dna2 DNA, rng *rand.Rand) DNA {
+	type key struct{ source, dest int }
+	other := make(map[key]SynapseGene, len(dna2.SynapseMap))
+	for _, syn := range sortedSynapses(dna2.SynapseMap) {
+		other[key{syn.SourceID, syn.DestID}] = syn
+	}
+	biases := make(map[int]float64, len(dna2.Neurons))
+	for _, ng := range dna2.Neurons {
+		biases[ng.ID] = ng.Bias
+	}
+
+	child := dna.Clone()
+	child.SynapseMap = make(map[SynapseGene]struct{}, len(dna.SynapseMap))
+	for _, syn := range sortedSynapses(dna.SynapseMap) {
+		if syn2, ok := other[key{syn.SourceID, syn.DestID}]; ok && rng.Float64() < 0.5 {
+			syn = syn2
+		}
+		child.SynapseMap[syn] = struct{}{}
+	}
+	for _, ng := range child.Neurons {
+		if bias, ok := biases[ng.ID]; ok && rng.Float64() < 0.5 {
+			ng.Bias = bias
+		}
+	}
+	return child
+}
+
 func (dna DNA) Clone() DNA {
 	var dna2 DNA
 	dna2.SynapseMap = make(map[SynapseGene]struct{}, len(dna.SynapseMap))
@@ -279,9 +441,13 @@ func (dna DNA) Clone() DNA {
 	dna2.Neurons = make([]*NeuronGene, 0, len(dna.Neurons))
 	for _, ng := range dna.Neurons {
 		dna2.Neurons = append(dna2.Neurons, &NeuronGene{
-			ID:    ng.ID,
-			Bias:  ng.Bias,
-			Layer: ng.Layer,
+			ID:         ng.ID,
+			Bias:       ng.Bias,
+			Layer:      ng.Layer,
+			Kind:       ng.Kind,
+			Tau:        ng.Tau,
+			Gates:      append([]float64(nil), ng.Gates...),
+			Activation: ng.Activation,
 		})
 	}
 
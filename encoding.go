@@ -0,0 +1,200 @@
+package net
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dnaJSON is the json layout of DNA, json has no maps with struct keys so the
+// synapses are stored as a sorted list
+type dnaJSON struct {
+	Neurons  []*NeuronGene
+	Synapses []SynapseGene
+}
+
+// MarshalJSON encodes the dna, synapses are sorted so equal dna encodes equally
+func (dna DNA) MarshalJSON() ([]byte, error) {
+	neurons := make([]*NeuronGene, len(dna.Neurons))
+	copy(neurons, dna.Neurons)
+	sort.Slice(neurons, func(i, j int) bool { return neurons[i].ID < neurons[j].ID })
+	return json.Marshal(dnaJSON{
+		Neurons:  neurons,
+		Synapses: sortedSynapses(dna.SynapseMap),
+	})
+}
+
+// UnmarshalJSON decodes dna encoded by MarshalJSON
+func (dna *DNA) UnmarshalJSON(data []byte) error {
+	var dj dnaJSON
+	if err := json.Unmarshal(data, &dj); err != nil {
+		return err
+	}
+	dna.Neurons = dj.Neurons
+	dna.SynapseMap = make(map[SynapseGene]struct{}, len(dj.Synapses))
+	for _, syn := range dj.Synapses {
+		dna.SynapseMap[syn] = struct{}{}
+	}
+	return nil
+}
+
+// sortedSynapses returns the synapses sorted by source, destination and weight
+func sortedSynapses(synapses map[SynapseGene]struct{}) []SynapseGene {
+	sorted := make([]SynapseGene, 0, len(synapses))
+	for syn := range synapses {
+		sorted = append(sorted, syn)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].SourceID != sorted[j].SourceID {
+			return sorted[i].SourceID < sorted[j].SourceID
+		}
+		if sorted[i].DestID != sorted[j].DestID {
+			return sorted[i].DestID < sorted[j].DestID
+		}
+		return sorted[i].Weight < sorted[j].Weight
+	})
+	return sorted
+}
+
+var dnaMagic = [4]byte{'N', 'E', 'T', 'D'}
+
+// MarshalBinary encodes the dna in a compact little endian format, synapses
+// are sorted so equal dna encodes equally
+func (dna DNA) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	w := func(v interface{}) {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	neurons := make([]*NeuronGene, len(dna.Neurons))
+	copy(neurons, dna.Neurons)
+	sort.Slice(neurons, func(i, j int) bool { return neurons[i].ID < neurons[j].ID })
+
+	w(dnaMagic)
+	w(uint32(len(neurons)))
+	for _, ng := range neurons {
+		w(int32(ng.ID))
+		w(ng.Bias)
+		w(uint8(ng.Layer))
+		w(uint8(ng.Kind))
+		w(uint8(ng.Activation))
+		w(ng.Tau)
+		w(uint32(len(ng.Gates)))
+		w(ng.Gates)
+	}
+	synapses := sortedSynapses(dna.SynapseMap)
+	w(uint32(len(synapses)))
+	for _, syn := range synapses {
+		w(int32(syn.SourceID))
+		w(int32(syn.DestID))
+		w(syn.Weight)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes dna encoded by MarshalBinary
+func (dna *DNA) UnmarshalBinary(data []byte) error {
+	buf := bytes.NewReader(data)
+	var err error
+	r := func(v interface{}) {
+		if err == nil {
+			err = binary.Read(buf, binary.LittleEndian, v)
+		}
+	}
+	length := func() int {
+		var l uint32
+		r(&l)
+		if err == nil && int(l) > len(data) {
+			err = fmt.Errorf("invalid length %d", l)
+		}
+		return int(l)
+	}
+
+	var magic [4]byte
+	r(&magic)
+	if err == nil && magic != dnaMagic {
+		return fmt.Errorf("not binary dna")
+	}
+	var dec DNA
+	dec.Neurons = make([]*NeuronGene, length())
+	for i := range dec.Neurons {
+		var id int32
+		var layer, kind, activation uint8
+		ng := &NeuronGene{}
+		r(&id)
+		r(&ng.Bias)
+		r(&layer)
+		r(&kind)
+		r(&activation)
+		r(&ng.Tau)
+		if l := length(); l > 0 && err == nil {
+			ng.Gates = make([]float64, l)
+			r(ng.Gates)
+		}
+		ng.ID, ng.Layer, ng.Kind, ng.Activation = int(id), int(layer), int(kind), int(activation)
+		dec.Neurons[i] = ng
+	}
+	synapses := length()
+	if err != nil {
+		return err
+	}
+	dec.SynapseMap = make(map[SynapseGene]struct{}, synapses)
+	for i := 0; i < synapses; i++ {
+		var source, dest int32
+		var weight float64
+		r(&source)
+		r(&dest)
+		r(&weight)
+		dec.SynapseMap[SynapseGene{SourceID: int(source), DestID: int(dest), Weight: weight}] = struct{}{}
+	}
+	if err != nil {
+		return err
+	}
+	if buf.Len() != 0 {
+		return fmt.Errorf("%d bytes after the dna", buf.Len())
+	}
+	*dna = dec
+	return nil
+}
+
+// DecodeDNA decodes dna in either the json or the binary format
+func DecodeDNA(data []byte) (DNA, error) {
+	var dna DNA
+	if bytes.HasPrefix(data, dnaMagic[:]) {
+		return dna, dna.UnmarshalBinary(data)
+	}
+	return dna, json.Unmarshal(data, &dna)
+}
+
+// LoadDNA reads a dna file in either format
+func LoadDNA(path string) (DNA, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DNA{}, err
+	}
+	dna, err := DecodeDNA(data)
+	if err != nil {
+		return dna, fmt.Errorf("unable to read dna %s: %w", path, err)
+	}
+	return dna, nil
+}
+
+// SaveDNA writes dna to path, in the binary format if path ends in .bin and
+// as indented json otherwise
+func SaveDNA(path string, dna DNA) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".bin") {
+		data, err = dna.MarshalBinary()
+	} else {
+		data, err = json.MarshalIndent(dna, "", "\t")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,49 @@
+package net
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNABinary(t *testing.T) {
+	n, err := NewBuilder().Size(2, 3, 2).HiddenKind(KindLSTM).Build()
+	require.NoError(t, err)
+	dna := NetToDna(n)
+	dna.Mutate(rand.New(rand.NewSource(1)))
+
+	data, err := dna.MarshalBinary()
+	require.NoError(t, err)
+	var dec DNA
+	require.NoError(t, dec.UnmarshalBinary(data))
+	assert.Equal(t, dna.SynapseMap, dec.SynapseMap)
+	assert.ElementsMatch(t, dna.Neurons, dec.Neurons)
+
+	again, err := dec.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	assert.Error(t, dec.UnmarshalBinary(data[:len(data)-1]))
+	assert.Error(t, dec.UnmarshalBinary(append(data, 0)))
+	assert.Error(t, dec.UnmarshalBinary([]byte("{}")))
+}
+
+func TestLoadDNA(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 1).Build()
+	require.NoError(t, err)
+	dna := NetToDna(n)
+	dir := t.TempDir()
+	for _, name := range []string{"net.json", "net.bin"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, SaveDNA(path, dna))
+		loaded, err := LoadDNA(path)
+		require.NoError(t, err, name)
+		assert.Equal(t, dna.SynapseMap, loaded.SynapseMap, name)
+		assert.ElementsMatch(t, dna.Neurons, loaded.Neurons, name)
+	}
+	_, err = LoadDNA(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Environment is a task a net acts in, one episode at a time
+type Environment interface {
+	// Reset starts a new episode, randomness should come from rng so episodes
+	// can be replayed
+	Reset(rng *rand.Rand)
+	// Observe returns the input for the net
+	Observe() []float64
+	// Step applies the action and returns the reward it earned and whether
+	// the episode is over
+	Step(action []float64) (reward float64, done bool)
+}
+
+// ActionMapper turns the output of a net into the action for an environment
+type ActionMapper func(output []float64, rng *rand.Rand) []float64
+
+// ArgmaxAction picks the largest output, the action is one-hot
+func ArgmaxAction(output []float64, rng *rand.Rand) []float64 {
+	action := make([]float64, len(output))
+	if len(output) > 0 {
+		action[Discrete(output)] = 1
+	}
+	return action
+}
+
+// SoftmaxAction samples an output with the softmax of the outputs divided by
+// temperature as probabilities, the action is one-hot
+func SoftmaxAction(temperature float64) ActionMapper {
+	return func(output []float64, rng *rand.Rand) []float64 {
+		action := make([]float64, len(output))
+		if len(output) == 0 {
+			return action
+		}
+		max := output[Discrete(output)]
+		probs := make([]float64, len(output))
+		var sum float64
+		for i, v := range output {
+			probs[i] = math.Exp((v - max) / temperature)
+			sum += probs[i]
+		}
+		r := rng.Float64() * sum
+		for i, p := range probs {
+			r -= p
+			if r < 0 {
+				action[i] = 1
+				return action
+			}
+		}
+		action[len(action)-1] = 1
+		return action
+	}
+}
+
+// ThresholdAction sets every output above threshold to 1 and the others to 0,
+// for environments that take several on/off actions at once
+func ThresholdAction(threshold float64) ActionMapper {
+	return func(output []float64, rng *rand.Rand) []float64 {
+		action := make([]float64, len(output))
+		for i, v := range output {
+			if v > threshold {
+				action[i] = 1
+			}
+		}
+		return action
+	}
+}
+
+// ContinuousAction scales outputs in [-1, 1], the range of the default
+// activation, to [low, high]
+func ContinuousAction(low, high float64) ActionMapper {
+	return func(output []float64, rng *rand.Rand) []float64 {
+		action := make([]float64, len(output))
+		for i, v := range output {
+			v = math.Max(-1, math.Min(1, v))
+			action[i] = low + (v+1)/2*(high-low)
+		}
+		return action
+	}
+}
+
+// Discrete returns the index of the largest value of a one-hot or raw action
+func Discrete(action []float64) int {
+	best := 0
+	for i, v := range action {
+		if v > action[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Runner drives a net through episodes of an environment and sums the
+// rewards. Every Run uses the same Seed, so all nets get the same episodes.
+type Runner struct {
+	// Env creates the environment, every Run gets its own so Runs can happen
+	// in parallel
+	Env      func() Environment
+	Episodes int
+	// MaxSteps ends an episode that isn't done, 0 means no limit
+	MaxSteps int
+	// Action maps the output to an action, nil passes the output unchanged
+	Action ActionMapper
+	// Discount weighs the reward of step t by Discount^t
+	Discount float64
+	Seed     int64
+}
+
+// NewRunner returns a Runner with default settings
+func NewRunner(env func() Environment) *Runner {
+	return &Runner{
+		Env:      env,
+		Episodes: 1,
+		MaxSteps: 1000,
+		Discount: 1,
+		Seed:     1,
+	}
+}
+
+// Run returns the return of every episode. The net is Reset at the start of
+// every episode.
+func (r *Runner) Run(n *Net) ([]float64, error) {
+	if r.Env == nil {
+		return nil, fmt.Errorf("no environment set")
+	}
+	if r.Episodes < 1 {
+		return nil, fmt.Errorf("episodes should be > 0, got %d", r.Episodes)
+	}
+	rng := rand.New(rand.NewSource(r.Seed))
+	env := r.Env()
+	returns := make([]float64, r.Episodes)
+	for ep := range returns {
+		n.Reset()
+		env.Reset(rng)
+		discount := 1.0
+		for step := 0; r.MaxSteps == 0 || step < r.MaxSteps; step++ {
+			out, err := n.Eval(env.Observe())
+			if err != nil {
+				return nil, err
+			}
+			action := out
+			if r.Action != nil {
+				action = r.Action(out, rng)
+			}
+			reward, done := env.Step(action)
+			returns[ep] += discount * reward
+			discount *= r.Discount
+			if done {
+				break
+			}
+		}
+	}
+	return returns, nil
+}
+
+// Fitness is the negated mean return, so minimizing it maximizes the return
+func (r *Runner) Fitness(n *Net) (float64, error) {
+	returns, err := r.Run(n)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	for _, ret := range returns {
+		sum += ret
+	}
+	return -sum / float64(len(returns)), nil
+}
+
+// DNAFitness is Fitness for Evolution, NSGA2 or MapElites
+func (r *Runner) DNAFitness(dna DNA) (float64, error) {
+	n, err := DNAToNet(dna)
+	if err != nil {
+		return 0, err
+	}
+	return r.Fitness(n)
+}
+
+// ParamsFitness is Fitness for the parameters of template, for OpenAIES and
+// CMAES
+func (r *Runner) ParamsFitness(template DNA) func([]float64) (float64, error) {
+	return paramsFitness(template, r.Fitness)
+}
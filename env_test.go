@@ -0,0 +1,75 @@
+package net
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countEnv rewards 1 per step and ends after length steps
+type countEnv struct {
+	length, steps int
+	actions       [][]float64
+}
+
+func (e *countEnv) Reset(rng *rand.Rand) { e.steps = 0 }
+func (e *countEnv) Observe() []float64   { return []float64{1, 0} }
+func (e *countEnv) Step(action []float64) (float64, bool) {
+	e.steps++
+	e.actions = append(e.actions, action)
+	return 1, e.steps == e.length
+}
+
+func TestActionMappers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	out := []float64{0.1, 0.9, -0.5}
+	assert.Equal(t, []float64{0, 1, 0}, ArgmaxAction(out, rng))
+	assert.Equal(t, []float64{0, 1, 0}, ThresholdAction(0.5)(out, rng))
+	assert.Equal(t, []float64{0, 1, 0}, SoftmaxAction(1e-6)(out, rng))
+	assert.InDeltaSlice(t, []float64{5.5, 9.5, 2.5}, ContinuousAction(0, 10)(out, rng), 1e-9)
+	assert.Equal(t, 1, Discrete(out))
+
+	counts := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		counts[Discrete(SoftmaxAction(1)(out, rng))]++
+	}
+	assert.Greater(t, counts[1], counts[0])
+	assert.Greater(t, counts[0], counts[2])
+}
+
+func TestRunner(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 2).Build()
+	require.NoError(t, err)
+	env := &countEnv{length: 3}
+	r := NewRunner(func() Environment { return env })
+	r.Episodes = 2
+	r.Action = ArgmaxAction
+
+	returns, err := r.Run(n)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 3}, returns)
+	assert.Len(t, env.actions, 6)
+	assert.Equal(t, 1.0, env.actions[0][0]+env.actions[0][1])
+
+	r.Discount = 0.5
+	fit, err := r.Fitness(n)
+	require.NoError(t, err)
+	assert.Equal(t, -1.75, fit)
+
+	r.Discount = 1
+	r.MaxSteps = 2
+	fit, err = r.DNAFitness(NetToDna(n))
+	require.NoError(t, err)
+	assert.Equal(t, -2.0, fit)
+
+	fit, err = r.ParamsFitness(NetToDna(n))(n.Params())
+	require.NoError(t, err)
+	assert.Equal(t, -2.0, fit)
+
+	// no episodes has no mean
+	r.Episodes = 0
+	_, err = r.Fitness(n)
+	assert.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// OpenAIES is the natural evolution strategy of Salimans et al. It estimates
+// the gradient of the fitness around Mean from Pairs antithetic samples,
+// shapes the fitnesses by rank and steps against the gradient, as it
+// minimizes. Use Net.Params and Net.SetParams to optimize a net.
+type OpenAIES struct {
+	Sigma        float64
+	LearningRate float64
+	// Pairs is the amount of antithetic sample pairs per generation
+	Pairs       int
+	Generations int
+	// Workers is the amount of fitness evaluations run in parallel, 0 uses
+	// GOMAXPROCS
+	Workers int
+	Seed    int64
+
+	// Callback is called after every generation, EarlyStop before every
+	// generation
+	Callback  func(*OpenAIES)
+	EarlyStop func(*OpenAIES) bool
+
+	Mean        []float64
+	Best        []float64
+	BestFitness float64
+	Generation  int
+}
+
+// NewOpenAIES returns an OpenAIES with default settings
+func NewOpenAIES() *OpenAIES {
+	return &OpenAIES{
+		Sigma:        0.1,
+		LearningRate: 0.05,
+		Pairs:        50,
+		Generations:  100,
+		Seed:         1,
+	}
+}
+
+// Minimize optimizes f starting from x0
+func (es *OpenAIES) Minimize(x0 []float64, f func([]float64) (float64, error)) error {
+	if es.Pairs < 1 {
+		return fmt.Errorf("Pairs should be > 0")
+	}
+	rng := rand.New(rand.NewSource(es.Seed))
+	es.Mean = append([]float64(nil), x0...)
+	es.Best = append([]float64(nil), x0...)
+	es.Generation = 0
+	fit, err := f(x0)
+	if err != nil {
+		return err
+	}
+	es.BestFitness = fit
+
+	for es.Generation < es.Generations {
+		if es.EarlyStop != nil && es.EarlyStop(es) {
+			return nil
+		}
+
+		noise := make([][]float64, es.Pairs)
+		points := make([][]float64, 0, 2*es.Pairs)
+		for i := range noise {
+			noise[i] = make([]float64, len(es.Mean))
+			plus := make([]float64, len(es.Mean))
+			minus := make([]float64, len(es.Mean))
+			for j := range noise[i] {
+				noise[i][j] = rng.NormFloat64()
+				plus[j] = es.Mean[j] + es.Sigma*noise[i][j]
+				minus[j] = es.Mean[j] - es.Sigma*noise[i][j]
+			}
+			points = append(points, plus, minus)
+		}
+		fits, err := evalPoints(points, es.Workers, f)
+		if err != nil {
+			return err
+		}
+		for i, fit := range fits {
+			if fit < es.BestFitness {
+				es.BestFitness = fit
+				es.Best = append(es.Best[:0], points[i]...)
+			}
+		}
+
+		utils := centeredRanks(fits)
+		for i := range noise {
+			diff := utils[2*i] - utils[2*i+1]
+			for j := range es.Mean {
+				es.Mean[j] -= es.LearningRate * diff * noise[i][j] / (float64(2*es.Pairs) * es.Sigma)
+			}
+		}
+		es.Generation++
+		if es.Callback != nil {
+			es.Callback(es)
+		}
+	}
+	return nil
+}
+
+// centeredRanks replaces every fitness by its rank scaled to [-0.5, 0.5], so
+// the update doesn't depend on the fitness' scale
+func centeredRanks(fits []float64) []float64 {
+	idx := make([]int, len(fits))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return fits[idx[i]] < fits[idx[j]] })
+	ranks := make([]float64, len(fits))
+	if len(fits) < 2 {
+		return ranks
+	}
+	for rank, i := range idx {
+		ranks[i] = float64(rank)/float64(len(fits)-1) - 0.5
+	}
+	return ranks
+}
+
+// evalPoints evaluates f for every point using the worker pool
+func evalPoints(points [][]float64, workers int, f func([]float64) (float64, error)) ([]float64, error) {
+	fits := make([]float64, len(points))
+	errs := make([]error, len(points))
+	parallel(len(points), workers, func(i int) {
+		fits[i], errs[i] = f(points[i])
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, fit := range fits {
+		if math.IsNaN(fit) {
+			fits[i] = math.Inf(1)
+		}
+	}
+	return fits, nil
+}
+
+// OptimizeParams optimizes the weights and biases of n, keeping its topology,
+// with optimizer "es" (OpenAIES) or "cmaes" (CMAES) for generations
+// generations and sets the best ones found. fitness gets a copy of n with the
+// candidate params. progress, if not nil, is called after every generation
+// with the best fitness so far.
+func OptimizeParams(n *Net, optimizer string, generations int, fitness func(*Net) (float64, error), progress func(generation int, best float64)) error {
+	f := paramsFitness(NetToDna(n), fitness)
+	var best []float64
+	var err error
+	switch optimizer {
+	case "es":
+		es := NewOpenAIES()
+		es.Generations = generations
+		if progress != nil {
+			es.Callback = func(es *OpenAIES) { progress(es.Generation, es.BestFitness) }
+		}
+		err = es.Minimize(n.Params(), f)
+		best = es.Best
+	case "cmaes":
+		es := NewCMAES()
+		es.Generations = generations
+		if progress != nil {
+			es.Callback = func(es *CMAES) { progress(es.Generation, es.BestFitness) }
+		}
+		err = es.Minimize(n.Params(), f)
+		best = es.Best
+	default:
+		return fmt.Errorf("unknown optimizer %q", optimizer)
+	}
+	if err != nil {
+		return err
+	}
+	return n.SetParams(best)
+}
+
+// paramsFitness turns the fitness of a net into the fitness of the parameters
+// of template
+func paramsFitness(template DNA, fitness func(*Net) (float64, error)) func([]float64) (float64, error) {
+	return func(params []float64) (float64, error) {
+		n, err := DNAToNet(template)
+		if err != nil {
+			return 0, err
+		}
+		if err := n.SetParams(params); err != nil {
+			return 0, err
+		}
+		return fitness(n)
+	}
+}
@@ -0,0 +1,101 @@
+package net
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sphere(x []float64) (float64, error) {
+	var sum float64
+	for i, v := range x {
+		d := v - float64(i)/10
+		sum += d * d
+	}
+	return sum, nil
+}
+
+func TestNet_Params(t *testing.T) {
+	n, err := NewBuilder().Size(3, 4, 2).Build()
+	require.NoError(t, err)
+
+	params := n.Params()
+	assert.Len(t, params, 4+2+3*4+4*2)
+	for i := range params {
+		params[i] = float64(i)
+	}
+	require.NoError(t, n.SetParams(params))
+	assert.Equal(t, params, n.Params())
+
+	n2, err := DNAToNet(NetToDna(n))
+	require.NoError(t, err)
+	assert.Equal(t, params, n2.Params())
+
+	assert.Error(t, n.SetParams(params[1:]))
+	assert.Error(t, n.SetParams(append(params, 1)))
+}
+
+func TestOpenAIES(t *testing.T) {
+	es := NewOpenAIES()
+	es.Generations = 300
+	x0 := make([]float64, 5)
+	start, _ := sphere(x0)
+	require.NoError(t, es.Minimize(x0, sphere))
+	assert.Equal(t, 300, es.Generation)
+	assert.Less(t, es.BestFitness, start/100)
+	fit, _ := sphere(es.Mean)
+	assert.Less(t, fit, start/100)
+}
+
+func TestOptimizeParams(t *testing.T) {
+	fitness := func(n *Net) (float64, error) {
+		out, err := n.Eval([]float64{1, 1})
+		if err != nil {
+			return 0, err
+		}
+		return math.Abs(out[0] - 0.5), nil
+	}
+	for _, optimizer := range []string{"es", "cmaes"} {
+		n, err := NewBuilder().Size(2, 2, 1).Build()
+		require.NoError(t, err)
+		start, err := fitness(n)
+		require.NoError(t, err)
+		var calls int
+		require.NoError(t, OptimizeParams(n, optimizer, 20, fitness, func(int, float64) { calls++ }))
+		assert.Equal(t, 20, calls, optimizer)
+		fit, err := fitness(n)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, fit, start, optimizer)
+	}
+	n, err := NewBuilder().Build()
+	require.NoError(t, err)
+	assert.Error(t, OptimizeParams(n, "sgd", 1, nil, nil))
+}
+
+func TestCMAES(t *testing.T) {
+	es := NewCMAES()
+	es.Generations = 200
+	var calls int
+	es.Callback = func(*CMAES) { calls++ }
+	x0 := make([]float64, 5)
+	require.NoError(t, es.Minimize(x0, sphere))
+	assert.Equal(t, 200, calls)
+	assert.Less(t, es.BestFitness, 1e-8)
+}
+
+func TestEigenSym(t *testing.T) {
+	a := [][]float64{{4, 1, 2}, {1, 3, 0}, {2, 0, 5}}
+	vals, vecs := eigenSym(a)
+	// a v = λ v for every eigenvector column v
+	for k, val := range vals {
+		for i := range a {
+			var av float64
+			for j := range a {
+				av += a[i][j] * vecs[j][k]
+			}
+			assert.InDelta(t, val*vecs[i][k], av, 1e-9)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package net
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Evolution evolves DNA directly, without going through eaopt. Like eaopt it
+// minimizes the fitness. Given the same Seed and a deterministic Fitness a run
+// gives the same result, no matter how many Workers evaluate in parallel.
+type Evolution struct {
+	PopSize     int
+	Generations int
+	// Elitism is the amount of best individuals copied unchanged into the
+	// next generation
+	Elitism   int
+	Selector  Selector
+	CrossRate float64
+	MutRate   float64
+	// Workers is the amount of fitness evaluations run in parallel, 0 uses
+	// GOMAXPROCS
+	Workers int
+	Seed    int64
+
+	Fitness func(DNA) (float64, error)
+	// Evaluate, when set, replaces Fitness and gets the whole population at
+	// once, for fitnesses that depend on the other individuals such as
+	// tournaments. Elites are evaluated again every generation.
+	Evaluate func([]DNA) ([]float64, error)
+	// Mutate defaults to DNA.MutateAll, Cross to DNA.Mate
+	Mutate func(*DNA, *rand.Rand)
+	Cross  func(DNA, DNA, *rand.Rand) DNA
+	// Callback is called after every generation, EarlyStop before every
+	// generation
+	Callback  func(*Evolution)
+	EarlyStop func(*Evolution) bool
+	// Novelty, when set, ranks the population on fitness and novelty instead
+	// of fitness only
+	Novelty *Novelty
+
+	// Population is sorted from best to worst
+	Population []Individual
+	Best       Individual
+	Generation int
+
+	rng *rand.Rand
+}
+
+// Individual is a DNA and its fitness. Score is what the population is
+// ranked on, it equals Fitness unless Evolution.Novelty is set.
+type Individual struct {
+	DNA     DNA
+	Fitness float64
+	Score   float64
+}
+
+// NewEvolution returns an Evolution with default settings
+func NewEvolution(fitness func(DNA) (float64, error)) *Evolution {
+	return &Evolution{
+		PopSize:     100,
+		Generations: 100,
+		Elitism:     1,
+		Selector:    SelTournament{Contestants: 3},
+		CrossRate:   0.5,
+		MutRate:     1,
+		Seed:        1,
+		Fitness:     fitness,
+	}
+}
+
+// Minimize runs the evolution, newDNA creates the initial population
+func (e *Evolution) Minimize(newDNA func(rng *rand.Rand) DNA) error {
+	if e.Fitness == nil && e.Evaluate == nil {
+		return fmt.Errorf("no fitness function set")
+	}
+	if e.PopSize < 1 {
+		return fmt.Errorf("PopSize should be > 0")
+	}
+	if e.Elitism < 0 || e.Elitism > e.PopSize {
+		return fmt.Errorf("Elitism should be between 0 and PopSize")
+	}
+	if e.Selector == nil {
+		e.Selector = SelTournament{Contestants: 3}
+	}
+	if e.Mutate == nil {
+		e.Mutate = (*DNA).MutateAll
+	}
+	if e.Cross == nil {
+		e.Cross = func(a, b DNA, rng *rand.Rand) DNA { return a.Mate(b, rng) }
+	}
+	e.rng = rand.New(rand.NewSource(e.Seed))
+	e.Generation = 0
+
+	e.Population = make([]Individual, e.PopSize)
+	for i := range e.Population {
+		e.Population[i].DNA = newDNA(e.rng)
+	}
+	if err := e.evaluate(e.Population); err != nil {
+		return err
+	}
+	if err := e.rank(e.Population); err != nil {
+		return err
+	}
+	e.sort()
+	if e.Callback != nil {
+		e.Callback(e)
+	}
+
+	for e.Generation < e.Generations {
+		if e.EarlyStop != nil && e.EarlyStop(e) {
+			return nil
+		}
+		if err := e.evolve(); err != nil {
+			return err
+		}
+		if e.Callback != nil {
+			e.Callback(e)
+		}
+	}
+	return nil
+}
+
+func (e *Evolution) evolve() error {
+	next := make([]Individual, 0, e.PopSize)
+	for _, elite := range e.Population[:e.Elitism] {
+		next = append(next, Individual{DNA: elite.DNA.Clone(), Fitness: elite.Fitness, Score: elite.Score})
+	}
+	for len(next) < e.PopSize {
+		child := e.Population[e.Selector.Select(e.Population, e.rng)].DNA.Clone()
+		if e.rng.Float64() < e.CrossRate {
+			mate := e.Population[e.Selector.Select(e.Population, e.rng)].DNA
+			child = e.Cross(child, mate, e.rng)
+		}
+		if e.rng.Float64() < e.MutRate {
+			e.Mutate(&child, e.rng)
+		}
+		next = append(next, Individual{DNA: child})
+	}
+	evaluate := next[e.Elitism:]
+	if e.Evaluate != nil {
+		evaluate = next
+	}
+	if err := e.evaluate(evaluate); err != nil {
+		return err
+	}
+	if err := e.rank(next); err != nil {
+		return err
+	}
+	e.Population = next
+	e.sort()
+	e.Generation++
+	return nil
+}
+
+// evaluate sets the fitness of the individuals using the worker pool
+func (e *Evolution) evaluate(indis []Individual) error {
+	if e.Evaluate != nil {
+		dnas := make([]DNA, len(indis))
+		for i := range indis {
+			dnas[i] = indis[i].DNA
+		}
+		fits, err := e.Evaluate(dnas)
+		if err != nil {
+			return err
+		}
+		if len(fits) != len(indis) {
+			return fmt.Errorf("got %d fitnesses for %d individuals", len(fits), len(indis))
+		}
+		for i := range indis {
+			indis[i].Fitness = fits[i]
+		}
+		return nil
+	}
+	errs := make([]error, len(indis))
+	parallel(len(indis), e.Workers, func(i int) {
+		indis[i].Fitness, errs[i] = e.Fitness(indis[i].DNA)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rank sets the score the individuals are sorted on
+func (e *Evolution) rank(indis []Individual) error {
+	if e.Novelty != nil {
+		return e.Novelty.apply(indis, e.Workers)
+	}
+	for i := range indis {
+		indis[i].Score = indis[i].Fitness
+	}
+	return nil
+}
+
+func (e *Evolution) sort() {
+	sort.SliceStable(e.Population, func(i, j int) bool {
+		return e.Population[i].Score < e.Population[j].Score
+	})
+	// with novelty the best ranked isn't necessarily the fittest
+	best := 0
+	for i, indi := range e.Population {
+		if indi.Fitness < e.Population[best].Fitness {
+			best = i
+		}
+	}
+	if e.Generation == 0 || e.Population[best].Fitness < e.Best.Fitness {
+		e.Best = e.Population[best]
+		e.Best.DNA = e.Best.DNA.Clone()
+	}
+}
+
+// parallel calls f for 0 <= i < n on a pool of workers, 0 workers uses
+// GOMAXPROCS
+func parallel(n, workers int, f func(i int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Selector picks a parent from a population sorted from best to worst and
+// returns its index
+type Selector interface {
+	Select(pop []Individual, rng *rand.Rand) int
+}
+
+// SelTournament picks the best of Contestants random individuals
+type SelTournament struct {
+	Contestants int
+}
+
+func (s SelTournament) Select(pop []Individual, rng *rand.Rand) int {
+	best := rng.Intn(len(pop))
+	for i := 1; i < s.Contestants; i++ {
+		// pop is sorted, so the lowest index is the fittest
+		if c := rng.Intn(len(pop)); c < best {
+			best = c
+		}
+	}
+	return best
+}
+
+// SelTruncation picks a random individual from the best Fraction of the
+// population
+type SelTruncation struct {
+	Fraction float64
+}
+
+func (s SelTruncation) Select(pop []Individual, rng *rand.Rand) int {
+	n := int(float64(len(pop)) * s.Fraction)
+	if n < 1 {
+		n = 1
+	}
+	if n > len(pop) {
+		n = len(pop)
+	}
+	return rng.Intn(n)
+}
+
+// SelRoulette picks individuals with a probability proportional to how much
+// better they score than the worst individual
+type SelRoulette struct{}
+
+func (s SelRoulette) Select(pop []Individual, rng *rand.Rand) int {
+	worst := pop[len(pop)-1].Score
+	var total float64
+	for _, indi := range pop {
+		total += worst - indi.Score
+	}
+	if total <= 0 {
+		return rng.Intn(len(pop))
+	}
+	r := rng.Float64() * total
+	for i, indi := range pop {
+		r -= worst - indi.Score
+		if r < 0 {
+			return i
+		}
+	}
+	return 0
+}
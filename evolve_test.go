@@ -0,0 +1,131 @@
+package net
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDNA(rng *rand.Rand) DNA {
+	r := func() float64 { return rng.Float64()*2 - 1 }
+	n, _ := NewBuilder().Size(2, 2, 1).WeightFunc(r).BiasFunc(r).Build()
+	return NetToDna(n)
+}
+
+// fitness is the distance of the net's output to 0.5 for input {1, 1}
+func testFitness(dna DNA) (float64, error) {
+	n, err := DNAToNet(dna)
+	if err != nil {
+		return 0, err
+	}
+	out, err := n.Eval([]float64{1, 1})
+	if err != nil {
+		return 0, err
+	}
+	return math.Abs(out[0] - 0.5), nil
+}
+
+func TestEvolution(t *testing.T) {
+	e := NewEvolution(testFitness)
+	e.PopSize = 30
+	e.Generations = 20
+	var calls int
+	e.Callback = func(e *Evolution) { calls++ }
+	require.NoError(t, e.Minimize(newTestDNA))
+
+	assert.Equal(t, 21, calls)
+	assert.Equal(t, 20, e.Generation)
+	assert.Len(t, e.Population, 30)
+	assert.LessOrEqual(t, e.Best.Fitness, e.Population[0].Fitness)
+	for i := 1; i < len(e.Population); i++ {
+		assert.LessOrEqual(t, e.Population[i-1].Fitness, e.Population[i].Fitness)
+	}
+	assert.Less(t, e.Best.Fitness, 0.05)
+}
+
+func TestEvolution_deterministic(t *testing.T) {
+	run := func(workers int, selector Selector) []byte {
+		e := NewEvolution(testFitness)
+		e.PopSize = 20
+		e.Generations = 10
+		e.Workers = workers
+		e.Selector = selector
+		require.NoError(t, e.Minimize(newTestDNA))
+		data, err := json.Marshal(e.Best.DNA)
+		require.NoError(t, err)
+		return data
+	}
+	for _, sel := range []Selector{SelTournament{Contestants: 3}, SelTruncation{Fraction: 0.3}, SelRoulette{}} {
+		assert.Equal(t, run(1, sel), run(8, sel))
+	}
+}
+
+func TestEvolution_EarlyStop(t *testing.T) {
+	e := NewEvolution(testFitness)
+	e.PopSize = 10
+	e.EarlyStop = func(e *Evolution) bool { return e.Generation == 3 }
+	require.NoError(t, e.Minimize(newTestDNA))
+	assert.Equal(t, 3, e.Generation)
+}
+
+func TestSelTruncation(t *testing.T) {
+	pop := make([]Individual, 10)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		assert.Less(t, SelTruncation{Fraction: 0.2}.Select(pop, rng), 2)
+	}
+}
+
+func TestMutateAll(t *testing.T) {
+	dna := newTestDNA(rand.New(rand.NewSource(1)))
+	a, b := dna.Clone(), dna.Clone()
+	a.MutateAll(rand.New(rand.NewSource(2)))
+	b.MutateAll(rand.New(rand.NewSource(2)))
+	assert.Equal(t, a.SynapseMap, b.SynapseMap)
+	assert.Equal(t, a.Neurons, b.Neurons)
+
+	// Mutate keeps the weights of the existing synapses
+	old := dna.Clone()
+	old.Mutate(rand.New(rand.NewSource(2)))
+	for syn := range dna.SynapseMap {
+		assert.Contains(t, old.SynapseMap, syn)
+		assert.NotContains(t, a.SynapseMap, syn)
+	}
+}
+
+func TestMate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	a, b := newTestDNA(rng), newTestDNA(rng)
+	b.SynapseMap[SynapseGene{SourceID: 10, DestID: 4, Weight: 1}] = struct{}{}
+
+	child := a.Mate(b, rng)
+	assert.Len(t, child.SynapseMap, len(a.SynapseMap))
+	assert.Len(t, child.Neurons, len(a.Neurons))
+	_, err := DNAToNet(child)
+	assert.NoError(t, err)
+}
+
+func TestEvolution_Evaluate(t *testing.T) {
+	var sizes []int
+	e := NewEvolution(nil)
+	e.PopSize = 10
+	e.Generations = 3
+	e.Evaluate = func(dnas []DNA) ([]float64, error) {
+		sizes = append(sizes, len(dnas))
+		fits := make([]float64, len(dnas))
+		for i := range dnas {
+			fits[i] = float64(i)
+		}
+		return fits, nil
+	}
+	require.NoError(t, e.Minimize(newTestDNA))
+	// the elites are evaluated again with the rest of the population
+	assert.Equal(t, []int{10, 10, 10, 10}, sizes)
+
+	e.Evaluate = func(dnas []DNA) ([]float64, error) { return nil, nil }
+	assert.Error(t, e.Minimize(newTestDNA))
+}
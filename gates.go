@@ -0,0 +1,57 @@
+package net
+
+import "math"
+
+// Gated neurons keep a hidden state h between evaluations and use it, together
+// with their summed input x, to decide how much to remember. Every gate has a
+// weight for x, a weight for h and a bias, stored in that order in
+// NeuronGene.Gates.
+//
+// GRU gates: update z, reset r, candidate h~
+//	z = σ(Wz x + Uz h + bz)
+//	r = σ(Wr x + Ur h + br)
+//	h~ = tanh(Wh x + Uh r h + bh)
+//	h = (1 - z) h + z h~
+//
+// LSTM gates: input i, forget f, output o, candidate g
+//	c = σ(f) c + σ(i) tanh(g)
+//	h = σ(o) tanh(c)
+
+// gateCount returns the amount of gate parameters a neuron kind needs
+func gateCount(kind int) int {
+	switch kind {
+	case KindGRU:
+		return 9
+	case KindLSTM:
+		return 12
+	}
+	return 0
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// gate returns the pre-activation of gate i
+func (n *neuron) gate(i int, x, h float64) float64 {
+	g := n.gates[i*3 : i*3+3]
+	return g[0]*x + g[1]*h + g[2]
+}
+
+func (n *neuron) gru(x float64) float64 {
+	z := logistic(n.gate(0, x, n.h))
+	r := logistic(n.gate(1, x, n.h))
+	candidate := math.Tanh(n.gate(2, x, r*n.h))
+	n.h = (1-z)*n.h + z*candidate
+	return n.h
+}
+
+func (n *neuron) lstm(x float64) float64 {
+	i := logistic(n.gate(0, x, n.h))
+	f := logistic(n.gate(1, x, n.h))
+	o := logistic(n.gate(2, x, n.h))
+	g := math.Tanh(n.gate(3, x, n.h))
+	n.c = f*n.c + i*g
+	n.h = o * math.Tanh(n.c)
+	return n.h
+}
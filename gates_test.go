@@ -0,0 +1,96 @@
+package net
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRU(t *testing.T) {
+	n, err := NewBuilder().
+		ActivationFunc(simple).
+		WeightFunc(fakeWeight).
+		HiddenKind(KindGRU).
+		Build()
+	require.NoError(t, err)
+	for _, neur := range n.neuronStore {
+		neur.bias = 1
+	}
+	for _, neur := range n.hidden {
+		// z and r stay at 0.5, the candidate is tanh(x)
+		neur.bias = 0
+		neur.gates = []float64{0, 0, 0, 0, 0, 0, 1, 0, 0}
+	}
+
+	out, err := n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.InDelta(t, 2*0.5*math.Tanh(2)+1, out[0], 1e-12)
+
+	out, err = n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.InDelta(t, 2*0.75*math.Tanh(2)+1, out[0], 1e-12)
+}
+
+func TestLSTM_remembers(t *testing.T) {
+	dna := DNA{
+		Neurons: []*NeuronGene{
+			{ID: 0, Layer: inputLayer},
+			{ID: 1, Layer: hiddenLayer, Kind: KindLSTM, Gates: []float64{
+				10, 0, -5, // input gate only opens on a pulse
+				0, 0, 10, // always remember
+				0, 0, 10, // always output
+				10, 0, 0,
+			}},
+			{ID: 2, Layer: outputLayer, Bias: 1},
+		},
+		SynapseMap: map[SynapseGene]struct{}{
+			{SourceID: 0, DestID: 1, Weight: 1}: {},
+			{SourceID: 1, DestID: 2, Weight: 1}: {},
+		},
+	}
+	n, err := DNAToNet(dna)
+	require.NoError(t, err)
+	n.StepMode(1)
+
+	_, err = n.Eval([]float64{1})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = n.Eval([]float64{0})
+		require.NoError(t, err)
+	}
+	assert.Greater(t, n.neuronStore[1].h, 0.5)
+
+	n.Reset()
+	for i := 0; i < 10; i++ {
+		_, err = n.Eval([]float64{0})
+		require.NoError(t, err)
+	}
+	assert.Less(t, n.neuronStore[1].h, 0.1)
+}
+
+func TestGates_DNA(t *testing.T) {
+	n, err := NewBuilder().HiddenKind(KindLSTM).Build()
+	require.NoError(t, err)
+
+	dna := NetToDna(n)
+	for _, ng := range dna.Neurons {
+		if ng.Layer == hiddenLayer {
+			assert.Len(t, ng.Gates, gateCount(KindLSTM))
+		}
+	}
+	clone := dna.Clone()
+	clone.Mutate(rand.New(rand.NewSource(1)))
+	_, err = DNAToNet(clone)
+	assert.NoError(t, err)
+
+	for _, ng := range dna.Neurons {
+		if ng.Layer == hiddenLayer {
+			ng.Gates = ng.Gates[1:]
+		}
+	}
+	_, err = DNAToNet(dna)
+	assert.Error(t, err)
+}
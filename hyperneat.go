@@ -0,0 +1,165 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// cppnActivations are the activations a CPPN's hidden neurons pick from, they
+// give the patterns symmetry, repetition and locality
+var cppnActivations = []int{ActSigmoid, ActTanh, ActSin, ActGaussian, ActAbs, ActLinear}
+
+// Substrate places the neurons of a network in space. A CPPN, itself a Net,
+// is queried with the coordinates of two neurons to get the weight of the
+// synapse between them, so a small evolved CPPN can describe a large network
+// with regular, geometric connectivity.
+//
+// The CPPN's input is the source coordinates, the destination coordinates and
+// a constant 1. Its first output is the weight, a second output, if it has
+// one, is the bias of the destination neuron, queried with zero source
+// coordinates.
+type Substrate struct {
+	Inputs  [][]float64
+	Hidden  [][]float64
+	Outputs [][]float64
+	// Threshold is the CPPN output below which no synapse is expressed
+	Threshold float64
+	// MaxWeight is the weight of a synapse for a CPPN output of 1
+	MaxWeight float64
+}
+
+// CPPNInSize returns the input size of the CPPNs the substrate accepts
+func (s Substrate) CPPNInSize() int {
+	return 2*s.dim() + 1
+}
+
+func (s Substrate) dim() int {
+	for _, layer := range [][][]float64{s.Inputs, s.Hidden, s.Outputs} {
+		if len(layer) > 0 {
+			return len(layer[0])
+		}
+	}
+	return 0
+}
+
+// Build queries the CPPN for every pair of neurons in consecutive layers and
+// returns the resulting network
+func (s Substrate) Build(cppn *Net) (*Net, error) {
+	dna, err := s.BuildDNA(cppn)
+	if err != nil {
+		return nil, err
+	}
+	return DNAToNet(dna)
+}
+
+// BuildDNA is like Build but returns the network's dna
+func (s Substrate) BuildDNA(cppn *Net) (dna DNA, err error) {
+	if len(s.Inputs) == 0 || len(s.Outputs) == 0 {
+		return dna, fmt.Errorf("substrate needs inputs and outputs")
+	}
+	dim := s.dim()
+	for _, layer := range [][][]float64{s.Inputs, s.Hidden, s.Outputs} {
+		for _, coord := range layer {
+			if len(coord) != dim {
+				return dna, fmt.Errorf("coordinates of different dimensions")
+			}
+		}
+	}
+	if s.Threshold < 0 || s.Threshold >= 1 {
+		return dna, fmt.Errorf("threshold should be in [0, 1)")
+	}
+	if cppn.InSize() != s.CPPNInSize() {
+		return dna, fmt.Errorf("cppn input size %d, expected %d", cppn.InSize(), s.CPPNInSize())
+	}
+	maxWeight := s.MaxWeight
+	if maxWeight == 0 {
+		maxWeight = 3
+	}
+
+	query := func(source, dest []float64) ([]float64, error) {
+		in := make([]float64, 0, s.CPPNInSize())
+		in = append(in, source...)
+		in = append(in, dest...)
+		in = append(in, 1)
+		return cppn.Eval(in)
+	}
+
+	dna.SynapseMap = make(map[SynapseGene]struct{})
+	layers := [][][]float64{s.Inputs, s.Hidden, s.Outputs}
+	kinds := []int{inputLayer, hiddenLayer, outputLayer}
+	var ids [][]int
+	var id int
+	for l, layer := range layers {
+		var layerIDs []int
+		for _, coord := range layer {
+			ng := &NeuronGene{ID: id, Layer: kinds[l], Bias: 1}
+			if l > 0 && cppn.OutSize() > 1 {
+				out, err := query(make([]float64, dim), coord)
+				if err != nil {
+					return dna, err
+				}
+				ng.Bias = out[1] * maxWeight
+			}
+			dna.Neurons = append(dna.Neurons, ng)
+			layerIDs = append(layerIDs, id)
+			id++
+		}
+		ids = append(ids, layerIDs)
+	}
+
+	connect := func(from, to int) error {
+		for i, source := range layers[from] {
+			for j, dest := range layers[to] {
+				out, err := query(source, dest)
+				if err != nil {
+					return err
+				}
+				w := out[0]
+				if math.Abs(w) <= s.Threshold {
+					continue
+				}
+				// scale the part above the threshold to the weight range
+				w = math.Copysign((math.Abs(w)-s.Threshold)/(1-s.Threshold), w) * maxWeight
+				dna.SynapseMap[SynapseGene{SourceID: ids[from][i], DestID: ids[to][j], Weight: w}] = struct{}{}
+			}
+		}
+		return nil
+	}
+	if len(s.Hidden) == 0 {
+		return dna, connect(0, 2)
+	}
+	if err := connect(0, 1); err != nil {
+		return dna, err
+	}
+	return dna, connect(1, 2)
+}
+
+// NewCPPN returns the dna of a random CPPN for the substrate with hidden
+// neurons of random activations, it can be used to create an Evolution's
+// initial population
+func (s Substrate) NewCPPN(hidden int, rng *rand.Rand) (DNA, error) {
+	r := func() float64 { return rng.Float64()*2 - 1 }
+	n, err := NewBuilder().Size(s.CPPNInSize(), hidden, 2).WeightFunc(r).BiasFunc(r).Build()
+	if err != nil {
+		return DNA{}, fmt.Errorf("unable to build the CPPN: %w", err)
+	}
+	dna := NetToDna(n)
+	for _, ng := range dna.Neurons {
+		if ng.Layer == hiddenLayer {
+			ng.Activation = cppnActivations[rng.Intn(len(cppnActivations))]
+		}
+	}
+	return dna, nil
+}
+
+// MutateCPPN mutates the dna like DNA.MutateAll and now and then gives a hidden
+// neuron another activation, use it as Evolution.Mutate when evolving CPPNs
+func MutateCPPN(dna *DNA, rng *rand.Rand) {
+	dna.MutateAll(rng)
+	for _, ng := range dna.Neurons {
+		if ng.Layer == hiddenLayer && rng.Float64() < 0.1 {
+			ng.Activation = cppnActivations[rng.Intn(len(cppnActivations))]
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package net
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func grid(size int, y float64) (coords [][]float64) {
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			coords = append(coords, []float64{float64(i)/float64(size-1)*2 - 1, float64(j)/float64(size-1)*2 - 1, y})
+		}
+	}
+	return coords
+}
+
+func TestSubstrate_Build(t *testing.T) {
+	s := Substrate{
+		Inputs:  grid(5, -1),
+		Hidden:  grid(3, 0),
+		Outputs: [][]float64{{-1, 0, 1}, {0, 0, 1}, {1, 0, 1}},
+	}
+	assert.Equal(t, 7, s.CPPNInSize())
+
+	rng := rand.New(rand.NewSource(1))
+	cppnDNA, err := s.NewCPPN(4, rng)
+	require.NoError(t, err)
+	cppn, err := DNAToNet(cppnDNA)
+	require.NoError(t, err)
+	_, err = s.NewCPPN(0, rng)
+	assert.Error(t, err)
+
+	n, err := s.Build(cppn)
+	require.NoError(t, err)
+	assert.Equal(t, 25, n.InSize())
+	assert.Equal(t, 3, n.OutSize())
+	assert.Len(t, n.synapses(), 25*9+9*3)
+	out, err := n.Eval(make([]float64, 25))
+	require.NoError(t, err)
+	assert.Len(t, out, 3)
+
+	s.Threshold = 0.5
+	dna, err := s.BuildDNA(cppn)
+	require.NoError(t, err)
+	assert.Less(t, len(dna.SynapseMap), 25*9+9*3)
+	for syn := range dna.SynapseMap {
+		assert.LessOrEqual(t, syn.Weight, 3.0)
+		assert.GreaterOrEqual(t, syn.Weight, -3.0)
+	}
+
+	s.Outputs = [][]float64{{1, 1}}
+	_, err = s.Build(cppn)
+	assert.Error(t, err)
+}
+
+func TestCPPN_activations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dna, err := Substrate{Inputs: grid(2, 0), Outputs: grid(2, 1)}.NewCPPN(20, rng)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		MutateCPPN(&dna, rng)
+	}
+
+	acts := make(map[int]bool)
+	for _, ng := range dna.Neurons {
+		acts[ng.Activation] = true
+	}
+	assert.Greater(t, len(acts), 1)
+
+	data, err := json.Marshal(dna)
+	require.NoError(t, err)
+	var dna2 DNA
+	require.NoError(t, json.Unmarshal(data, &dna2))
+	assert.ElementsMatch(t, dna.Neurons, dna2.Neurons)
+
+	dna2.Neurons[0].Activation = 100
+	_, err = DNAToNet(dna2)
+	assert.Error(t, err)
+}
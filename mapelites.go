@@ -0,0 +1,369 @@
+package net
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// MapElites keeps the best DNA found for every cell of a grid of behaviour
+// features, so it ends up with many different controllers that are each good
+// at what they do instead of one champion. Like the rest of the package it
+// minimizes the fitness.
+//
+// A MapElites that already holds elites, for example after Load, continues
+// from them instead of creating a new initial population.
+type MapElites struct {
+	Features []Feature
+	// Evaluate returns the fitness and the feature values of the dna, one
+	// value per feature. Dna with a value that isn't finite is dropped.
+	Evaluate func(DNA) (fitness float64, features []float64, err error)
+	// InitSize is the amount of random DNA evaluated to seed the grid
+	InitSize int
+	// Generations is the amount of batches of BatchSize offspring
+	Generations int
+	BatchSize   int
+	CrossRate   float64
+	// Workers is the amount of evaluations run in parallel, 0 uses GOMAXPROCS
+	Workers int
+	Seed    int64
+
+	// Mutate defaults to DNA.MutateAll, Cross to DNA.Mate
+	Mutate func(*DNA, *rand.Rand)
+	Cross  func(DNA, DNA, *rand.Rand) DNA
+	// Callback is called after every generation, EarlyStop before every
+	// generation
+	Callback  func(*MapElites)
+	EarlyStop func(*MapElites) bool
+
+	Generation int
+
+	elites map[int]Elite
+}
+
+// Feature is one axis of the grid, values outside [Min, Max] end up in the
+// first or last bin
+type Feature struct {
+	Name     string
+	Min, Max float64
+	Bins     int
+}
+
+// Elite is the best DNA found for a cell
+type Elite struct {
+	Cell     []int
+	Features []float64
+	Fitness  float64
+	DNA      DNA
+}
+
+// NewMapElites returns a MapElites with default settings
+func NewMapElites(features []Feature, evaluate func(DNA) (float64, []float64, error)) *MapElites {
+	return &MapElites{
+		Features:    features,
+		Evaluate:    evaluate,
+		InitSize:    100,
+		Generations: 100,
+		BatchSize:   100,
+		CrossRate:   0.2,
+		Seed:        1,
+	}
+}
+
+// Minimize fills the grid, newDNA creates the initial population
+func (me *MapElites) Minimize(newDNA func(rng *rand.Rand) DNA) error {
+	if me.Evaluate == nil {
+		return fmt.Errorf("no evaluate function set")
+	}
+	if err := me.validate(); err != nil {
+		return err
+	}
+	if me.Mutate == nil {
+		me.Mutate = (*DNA).MutateAll
+	}
+	if me.Cross == nil {
+		me.Cross = func(a, b DNA, rng *rand.Rand) DNA { return a.Mate(b, rng) }
+	}
+	// resumed runs get their own stream of random numbers
+	rng := rand.New(rand.NewSource(me.Seed + int64(me.Generation)))
+
+	if len(me.elites) == 0 {
+		batch := make([]DNA, me.InitSize)
+		for i := range batch {
+			batch[i] = newDNA(rng)
+		}
+		if err := me.insert(batch); err != nil {
+			return err
+		}
+	}
+	if len(me.elites) == 0 {
+		return fmt.Errorf("no elites to evolve")
+	}
+
+	for me.Generation < me.Generations {
+		if me.EarlyStop != nil && me.EarlyStop(me) {
+			return nil
+		}
+		elites := me.Elites()
+		batch := make([]DNA, me.BatchSize)
+		for i := range batch {
+			child := elites[rng.Intn(len(elites))].DNA.Clone()
+			if rng.Float64() < me.CrossRate {
+				child = me.Cross(child, elites[rng.Intn(len(elites))].DNA, rng)
+			}
+			me.Mutate(&child, rng)
+			batch[i] = child
+		}
+		if err := me.insert(batch); err != nil {
+			return err
+		}
+		me.Generation++
+		if me.Callback != nil {
+			me.Callback(me)
+		}
+	}
+	return nil
+}
+
+// insert evaluates the batch using the worker pool and keeps every dna that
+// beats the elite of its cell
+func (me *MapElites) insert(batch []DNA) error {
+	fits := make([]float64, len(batch))
+	features := make([][]float64, len(batch))
+	errs := make([]error, len(batch))
+	parallel(len(batch), me.Workers, func(i int) {
+		fits[i], features[i], errs[i] = me.Evaluate(batch[i])
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if me.elites == nil {
+		me.elites = make(map[int]Elite)
+	}
+	// in batch order, so the result doesn't depend on the workers
+	for i, dna := range batch {
+		if !finite(fits[i]) || !allFinite(features[i]) {
+			// it can't be saved
+			continue
+		}
+		cell, err := me.Cell(features[i])
+		if err != nil {
+			return err
+		}
+		key := me.key(cell)
+		if old, ok := me.elites[key]; ok && old.Fitness <= fits[i] {
+			continue
+		}
+		me.elites[key] = Elite{
+			Cell:     cell,
+			Features: append([]float64(nil), features[i]...),
+			Fitness:  fits[i],
+			DNA:      dna,
+		}
+	}
+	return nil
+}
+
+func finite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+func allFinite(fs []float64) bool {
+	for _, f := range fs {
+		if !finite(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cell returns the bin of every feature value
+func (me *MapElites) Cell(features []float64) ([]int, error) {
+	if len(features) != len(me.Features) {
+		return nil, fmt.Errorf("got %d feature values, expected %d", len(features), len(me.Features))
+	}
+	cell := make([]int, len(features))
+	for i, f := range me.Features {
+		bin := int(math.Floor((features[i] - f.Min) / (f.Max - f.Min) * float64(f.Bins)))
+		if bin < 0 || math.IsNaN(features[i]) {
+			bin = 0
+		}
+		if bin >= f.Bins {
+			bin = f.Bins - 1
+		}
+		cell[i] = bin
+	}
+	return cell, nil
+}
+
+// key flattens a cell to a single index
+func (me *MapElites) key(cell []int) int {
+	var key int
+	for i, f := range me.Features {
+		key = key*f.Bins + cell[i]
+	}
+	return key
+}
+
+// Elites returns the elites ordered by cell
+func (me *MapElites) Elites() []Elite {
+	keys := make([]int, 0, len(me.elites))
+	for key := range me.elites {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	elites := make([]Elite, len(keys))
+	for i, key := range keys {
+		elites[i] = me.elites[key]
+	}
+	return elites
+}
+
+// Best returns the fittest elite
+func (me *MapElites) Best() (Elite, bool) {
+	var best Elite
+	found := false
+	for _, elite := range me.Elites() {
+		if !found || elite.Fitness < best.Fitness {
+			best = elite
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Coverage returns the fraction of the cells that hold an elite
+func (me *MapElites) Coverage() float64 {
+	cells := 1
+	for _, f := range me.Features {
+		cells *= f.Bins
+	}
+	return float64(len(me.elites)) / float64(cells)
+}
+
+func (me *MapElites) validate() error {
+	if len(me.Features) == 0 {
+		return fmt.Errorf("no features set")
+	}
+	for _, f := range me.Features {
+		if f.Bins < 1 {
+			return fmt.Errorf("feature %q should have at least 1 bin", f.Name)
+		}
+		if !(f.Max > f.Min) {
+			return fmt.Errorf("feature %q should have Max > Min", f.Name)
+		}
+	}
+	return nil
+}
+
+// mapElitesJSON is the json layout of the archive
+type mapElitesJSON struct {
+	Features   []Feature
+	Generation int
+	Elites     []Elite
+}
+
+// WriteJSON writes the features and the elite grid as json
+func (me *MapElites) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(mapElitesJSON{
+		Features:   me.Features,
+		Generation: me.Generation,
+		Elites:     me.Elites(),
+	})
+}
+
+// ReadJSON replaces the features and elites by the ones written by WriteJSON
+func (me *MapElites) ReadJSON(r io.Reader) error {
+	var mj mapElitesJSON
+	if err := json.NewDecoder(r).Decode(&mj); err != nil {
+		return err
+	}
+	me.Features = mj.Features
+	if err := me.validate(); err != nil {
+		return err
+	}
+	elites := make(map[int]Elite, len(mj.Elites))
+	for _, elite := range mj.Elites {
+		if len(elite.Cell) != len(me.Features) {
+			return fmt.Errorf("elite has %d cell indices, expected %d", len(elite.Cell), len(me.Features))
+		}
+		for i, bin := range elite.Cell {
+			if bin < 0 || bin >= me.Features[i].Bins {
+				return fmt.Errorf("elite bin %d of feature %q out of range", bin, me.Features[i].Name)
+			}
+		}
+		elites[me.key(elite.Cell)] = elite
+	}
+	me.elites = elites
+	me.Generation = mj.Generation
+	return nil
+}
+
+// Save writes the archive to a json file
+func (me *MapElites) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := me.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads an archive written by Save, Minimize continues from it
+func (me *MapElites) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return me.ReadJSON(f)
+}
+
+// WriteCSV writes one row per elite with its bins, feature values, fitness
+// and size, without the dna
+func (me *MapElites) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	var header []string
+	for _, f := range me.Features {
+		header = append(header, f.Name+"_bin")
+	}
+	for _, f := range me.Features {
+		header = append(header, f.Name)
+	}
+	header = append(header, "fitness", "neurons", "synapses")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, elite := range me.Elites() {
+		var row []string
+		for _, bin := range elite.Cell {
+			row = append(row, strconv.Itoa(bin))
+		}
+		for _, v := range elite.Features {
+			row = append(row, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		row = append(row,
+			strconv.FormatFloat(elite.Fitness, 'g', -1, 64),
+			strconv.Itoa(len(elite.DNA.Neurons)),
+			strconv.Itoa(len(elite.DNA.SynapseMap)),
+		)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
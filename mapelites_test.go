@@ -0,0 +1,129 @@
+package net
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFeatures(dna DNA) (float64, []float64, error) {
+	fit, err := testFitness(dna)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := DNAToNet(dna)
+	if err != nil {
+		return 0, nil, err
+	}
+	out, err := n.Eval([]float64{1, 1})
+	if err != nil {
+		return 0, nil, err
+	}
+	return fit, []float64{out[0], float64(len(dna.SynapseMap))}, nil
+}
+
+func testMapElites() *MapElites {
+	me := NewMapElites([]Feature{
+		{Name: "out", Min: -1, Max: 1, Bins: 5},
+		{Name: "links", Min: 0, Max: 20, Bins: 4},
+	}, testFeatures)
+	me.InitSize = 20
+	me.BatchSize = 20
+	me.Generations = 5
+	return me
+}
+
+func TestMapElites_Cell(t *testing.T) {
+	me := testMapElites()
+	cell, err := me.Cell([]float64{-1, 20})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3}, cell)
+	cell, err = me.Cell([]float64{0.1, 7})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, cell)
+	cell, err = me.Cell([]float64{-5, 100})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3}, cell)
+	_, err = me.Cell([]float64{1})
+	assert.Error(t, err)
+}
+
+func TestMapElites(t *testing.T) {
+	run := func(workers int) *MapElites {
+		me := testMapElites()
+		me.Workers = workers
+		require.NoError(t, me.Minimize(newTestDNA))
+		return me
+	}
+	me := run(1)
+	assert.Equal(t, 5, me.Generation)
+	assert.Greater(t, me.Coverage(), 0.0)
+	for _, elite := range me.Elites() {
+		cell, err := me.Cell(elite.Features)
+		require.NoError(t, err)
+		assert.Equal(t, cell, elite.Cell)
+	}
+	var a, b bytes.Buffer
+	require.NoError(t, me.WriteJSON(&a))
+	require.NoError(t, run(8).WriteJSON(&b))
+	assert.Equal(t, a.String(), b.String())
+}
+
+func TestMapElites_SaveLoad(t *testing.T) {
+	me := testMapElites()
+	require.NoError(t, me.Minimize(newTestDNA))
+	path := filepath.Join(t.TempDir(), "archive.json")
+	require.NoError(t, me.Save(path))
+
+	loaded := testMapElites()
+	require.NoError(t, loaded.Load(path))
+	assert.Equal(t, me.Generation, loaded.Generation)
+	require.Len(t, loaded.Elites(), len(me.Elites()))
+	best, _ := me.Best()
+	loadedBest, _ := loaded.Best()
+	assert.Equal(t, best.Fitness, loadedBest.Fitness)
+
+	// continues from the loaded archive, elites only get better
+	loaded.Generations = 10
+	require.NoError(t, loaded.Minimize(nil))
+	assert.Equal(t, 10, loaded.Generation)
+	loadedBest, _ = loaded.Best()
+	assert.LessOrEqual(t, loadedBest.Fitness, best.Fitness)
+}
+
+func TestMapElites_WriteCSV(t *testing.T) {
+	me := testMapElites()
+	require.NoError(t, me.Minimize(newTestDNA))
+	var buf bytes.Buffer
+	require.NoError(t, me.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, "out_bin,links_bin,out,links,fitness,neurons,synapses", lines[0])
+	assert.Len(t, lines, len(me.Elites())+1)
+}
+
+func TestMapElites_nonFinite(t *testing.T) {
+	me := testMapElites()
+	values := [][2]float64{{math.Inf(-1), 0}, {math.NaN(), 0}, {0, math.Inf(1)}, {0, math.NaN()}, {1, 0}}
+	i := 0
+	me.Evaluate = func(dna DNA) (float64, []float64, error) {
+		v := values[i%len(values)]
+		i++
+		return v[0], []float64{v[1], 0}, nil
+	}
+	rng := rand.New(rand.NewSource(1))
+	var batch []DNA
+	for range values {
+		batch = append(batch, newTestDNA(rng))
+	}
+	require.NoError(t, me.insert(batch))
+	require.Len(t, me.Elites(), 1)
+	assert.Equal(t, 1.0, me.Elites()[0].Fitness)
+	var buf bytes.Buffer
+	assert.NoError(t, me.WriteJSON(&buf))
+}
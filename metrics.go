@@ -0,0 +1,434 @@
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MaxHalford/eaopt"
+)
+
+// GenerationMetrics describes one generation of a run
+type GenerationMetrics struct {
+	Generation int
+	Time       time.Time
+	// EvalSeconds is the wall time since the previous generation, which is
+	// mostly spent evaluating
+	EvalSeconds float64
+	Populations []PopulationMetrics
+	Mutations   MutationStats
+}
+
+// PopulationMetrics describes the fitness and size of a population, or island.
+// Best, Mean and Std are over the Finite fitnesses and 0 without any.
+type PopulationMetrics struct {
+	ID       string
+	Size     int
+	Finite   int
+	Best     float64
+	Mean     float64
+	Std      float64
+	Species  int
+	Neurons  float64
+	Synapses float64
+}
+
+// NewPopulationMetrics computes the metrics of a population from the fitness
+// and DNA of its individuals. Infinite and NaN fitnesses are left out, so the
+// metrics can be written as json. Species are counted with SpeciesThreshold.
+func NewPopulationMetrics(id string, fitness []float64, dnas []DNA) PopulationMetrics {
+	m := PopulationMetrics{ID: id, Size: len(fitness)}
+	var finite []float64
+	for _, f := range fitness {
+		if !math.IsInf(f, 0) && !math.IsNaN(f) {
+			finite = append(finite, f)
+		}
+	}
+	m.Finite = len(finite)
+	for i, f := range finite {
+		if i == 0 || f < m.Best {
+			m.Best = f
+		}
+		m.Mean += f
+	}
+	if len(finite) > 0 {
+		m.Mean /= float64(len(finite))
+		for _, f := range finite {
+			m.Std += (f - m.Mean) * (f - m.Mean)
+		}
+		m.Std = math.Sqrt(m.Std / float64(len(finite)))
+	}
+	for _, dna := range dnas {
+		m.Neurons += float64(len(dna.Neurons))
+		m.Synapses += float64(len(dna.SynapseMap))
+	}
+	if len(dnas) > 0 {
+		m.Neurons /= float64(len(dnas))
+		m.Synapses /= float64(len(dnas))
+	}
+	m.Species = Species(dnas, SpeciesThreshold)
+	return m
+}
+
+// EvolutionMetrics returns the metrics of the current generation of e
+func EvolutionMetrics(e *Evolution) GenerationMetrics {
+	fitness := make([]float64, len(e.Population))
+	dnas := make([]DNA, len(e.Population))
+	for i, indi := range e.Population {
+		fitness[i] = indi.Fitness
+		dnas[i] = indi.DNA
+	}
+	return GenerationMetrics{
+		Generation:  e.Generation,
+		Populations: []PopulationMetrics{NewPopulationMetrics("", fitness, dnas)},
+	}
+}
+
+// Best returns the lowest fitness over all populations, populations without a
+// finite fitness are skipped
+func (m GenerationMetrics) Best() float64 {
+	best := math.Inf(1)
+	for _, p := range m.Populations {
+		if p.Finite > 0 {
+			best = math.Min(best, p.Best)
+		}
+	}
+	return best
+}
+
+// SpeciesThreshold is the Compatibility below which two nets are of the same
+// species
+const SpeciesThreshold = 0.5
+
+// Compatibility is the NEAT distance between two nets, the fraction of
+// synapses only one of them has plus 0.4 times the mean weight difference of
+// the synapses they share. Synapses are matched on source and destination.
+func Compatibility(a, b DNA) float64 {
+	return compatibility(synapseWeights(a), synapseWeights(b))
+}
+
+// compatibility is Compatibility of the synapseWeights of two nets
+func compatibility(wa, wb map[[2]int]float64) float64 {
+	var disjoint, matching int
+	var diff float64
+	for k, w := range wa {
+		if w2, ok := wb[k]; ok {
+			matching++
+			diff += math.Abs(w - w2)
+		} else {
+			disjoint++
+		}
+	}
+	disjoint += len(wb) - matching
+	size := len(wa)
+	if len(wb) > size {
+		size = len(wb)
+	}
+	if size == 0 {
+		return 0
+	}
+	d := float64(disjoint) / float64(size)
+	if matching > 0 {
+		d += 0.4 * diff / float64(matching)
+	}
+	return d
+}
+
+func synapseWeights(dna DNA) map[[2]int]float64 {
+	weights := make(map[[2]int]float64, len(dna.SynapseMap))
+	for _, syn := range sortedSynapses(dna.SynapseMap) {
+		weights[[2]int{syn.SourceID, syn.DestID}] = syn.Weight
+	}
+	return weights
+}
+
+// Species counts the species in a population, every net joins the first
+// species whose first member is within threshold, or starts a new one
+func Species(dnas []DNA, threshold float64) int {
+	var reps []map[[2]int]float64
+	for _, dna := range dnas {
+		weights := synapseWeights(dna)
+		found := false
+		for _, rep := range reps {
+			if compatibility(weights, rep) < threshold {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reps = append(reps, weights)
+		}
+	}
+	return len(reps)
+}
+
+// MutationStats counts the mutations of a generation and the structural
+// changes they made
+type MutationStats struct {
+	Mutations       int
+	NeuronsAdded    int
+	SynapsesAdded   int
+	SynapsesRemoved int
+}
+
+// MutationCounter collects MutationStats, it's safe for concurrent use
+type MutationCounter struct {
+	mu    sync.Mutex
+	stats MutationStats
+}
+
+// Record counts a mutation from before to after
+func (c *MutationCounter) Record(before, after DNA) {
+	wb, wa := synapseWeights(before), synapseWeights(after)
+	var added, removed int
+	for k := range wa {
+		if _, ok := wb[k]; !ok {
+			added++
+		}
+	}
+	for k := range wb {
+		if _, ok := wa[k]; !ok {
+			removed++
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Mutations++
+	c.stats.SynapsesAdded += added
+	c.stats.SynapsesRemoved += removed
+	if d := len(after.Neurons) - len(before.Neurons); d > 0 {
+		c.stats.NeuronsAdded += d
+	}
+}
+
+// Wrap returns mutate that records every mutation, for Evolution.Mutate
+func (c *MutationCounter) Wrap(mutate func(*DNA, *rand.Rand)) func(*DNA, *rand.Rand) {
+	return func(dna *DNA, rng *rand.Rand) {
+		before := dna.Clone()
+		mutate(dna, rng)
+		c.Record(before, *dna)
+	}
+}
+
+// Take returns the stats since the previous Take
+func (c *MutationCounter) Take() MutationStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	c.stats = MutationStats{}
+	return stats
+}
+
+// Metrics formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+var metricsHeader = []string{
+	"generation", "time", "eval_seconds", "population", "size", "best", "mean", "std",
+	"species", "neurons", "synapses", "mutations", "neurons_added", "synapses_added", "synapses_removed",
+	"finite",
+}
+
+// MetricsRecorder writes the metrics of every generation, as a json line or
+// as csv rows, one per population
+type MetricsRecorder struct {
+	// Mutations is taken on every Record, count into it with Record or Wrap
+	Mutations MutationCounter
+
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	last   time.Time
+}
+
+// NewMetricsRecorder returns a recorder writing format to w
+func NewMetricsRecorder(w io.Writer, format string) (*MetricsRecorder, error) {
+	r := &MetricsRecorder{format: format, w: w, last: time.Now()}
+	switch format {
+	case FormatJSON:
+	case FormatCSV:
+		r.csv = csv.NewWriter(w)
+		if err := r.csv.Write(metricsHeader); err != nil {
+			return nil, err
+		}
+		r.csv.Flush()
+		if err := r.csv.Error(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown metrics format %q", format)
+	}
+	return r, nil
+}
+
+// Record writes m, Time, EvalSeconds and Mutations are filled in when they're
+// zero
+func (r *MetricsRecorder) Record(m GenerationMetrics) error {
+	now := time.Now()
+	if m.Time.IsZero() {
+		m.Time = now.UTC()
+	}
+	if m.EvalSeconds == 0 {
+		m.EvalSeconds = now.Sub(r.last).Seconds()
+	}
+	r.last = now
+	if m.Mutations == (MutationStats{}) {
+		m.Mutations = r.Mutations.Take()
+	}
+	if r.format == FormatJSON {
+		return json.NewEncoder(r.w).Encode(m)
+	}
+	for _, p := range m.Populations {
+		err := r.csv.Write([]string{
+			strconv.Itoa(m.Generation),
+			m.Time.Format(time.RFC3339Nano),
+			formatFloat(m.EvalSeconds),
+			p.ID,
+			strconv.Itoa(p.Size),
+			formatFloat(p.Best),
+			formatFloat(p.Mean),
+			formatFloat(p.Std),
+			strconv.Itoa(p.Species),
+			formatFloat(p.Neurons),
+			formatFloat(p.Synapses),
+			strconv.Itoa(m.Mutations.Mutations),
+			strconv.Itoa(m.Mutations.NeuronsAdded),
+			strconv.Itoa(m.Mutations.SynapsesAdded),
+			strconv.Itoa(m.Mutations.SynapsesRemoved),
+			strconv.Itoa(p.Finite),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	r.csv.Flush()
+	return r.csv.Error()
+}
+
+// RecordEvolution records the current generation of e, for Evolution.Callback
+func (r *MetricsRecorder) RecordEvolution(e *Evolution) error {
+	return r.Record(EvolutionMetrics(e))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// ReadMetrics reads a log written by a MetricsRecorder, in either format
+func ReadMetrics(r io.Reader) ([]GenerationMetrics, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(start, []byte("{")) {
+		return readMetricsJSON(br)
+	}
+	return readMetricsCSV(br)
+}
+
+func readMetricsJSON(r io.Reader) ([]GenerationMetrics, error) {
+	var metrics []GenerationMetrics
+	dec := json.NewDecoder(r)
+	for {
+		var m GenerationMetrics
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			return metrics, nil
+		}
+		if err != nil {
+			return metrics, fmt.Errorf("generation %d: %w", len(metrics), err)
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+func readMetricsCSV(r io.Reader) ([]GenerationMetrics, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	// logs from before the finite column have one column less
+	if len(rows[0]) < len(metricsHeader)-1 || len(rows[0]) > len(metricsHeader) || rows[0][0] != metricsHeader[0] {
+		return nil, fmt.Errorf("not a metrics csv")
+	}
+	var metrics []GenerationMetrics
+	for i, row := range rows[1:] {
+		var p PopulationMetrics
+		var m GenerationMetrics
+		var err error
+		ints := []*int{&m.Generation, &p.Size, &p.Species, &m.Mutations.Mutations,
+			&m.Mutations.NeuronsAdded, &m.Mutations.SynapsesAdded, &m.Mutations.SynapsesRemoved}
+		for j, col := range []int{0, 4, 8, 11, 12, 13, 14} {
+			if *ints[j], err = strconv.Atoi(row[col]); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+2, err)
+			}
+		}
+		floats := []*float64{&m.EvalSeconds, &p.Best, &p.Mean, &p.Std, &p.Neurons, &p.Synapses}
+		for j, col := range []int{2, 5, 6, 7, 9, 10} {
+			if *floats[j], err = strconv.ParseFloat(row[col], 64); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+2, err)
+			}
+		}
+		if m.Time, err = time.Parse(time.RFC3339Nano, row[1]); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+2, err)
+		}
+		p.ID = row[3]
+		p.Finite = p.Size
+		if len(row) == len(metricsHeader) {
+			if p.Finite, err = strconv.Atoi(row[15]); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+2, err)
+			}
+		}
+		if n := len(metrics); n > 0 && metrics[n-1].Generation == m.Generation {
+			metrics[n-1].Populations = append(metrics[n-1].Populations, p)
+			continue
+		}
+		m.Populations = []PopulationMetrics{p}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// GAMetrics returns the metrics of the current generation of an eaopt GA, one
+// population per island. toDNA gets the DNA of a genome.
+func GAMetrics(ga *eaopt.GA, toDNA func(eaopt.Genome) DNA) GenerationMetrics {
+	m := GenerationMetrics{Generation: int(ga.Generations)}
+	for _, pop := range ga.Populations {
+		fitness := make([]float64, len(pop.Individuals))
+		dnas := make([]DNA, len(pop.Individuals))
+		for i, indi := range pop.Individuals {
+			fitness[i] = indi.Fitness
+			dnas[i] = toDNA(indi.Genome)
+		}
+		m.Populations = append(m.Populations, NewPopulationMetrics(pop.ID, fitness, dnas))
+	}
+	return m
+}
+
+// MetricsFormat returns the format for a metrics file, csv for a .csv file and
+// json lines otherwise
+func MetricsFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSON
+}
@@ -0,0 +1,84 @@
+package net
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompatibility(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 1).Build()
+	require.NoError(t, err)
+	a := NetToDna(n)
+	assert.Equal(t, 0.0, Compatibility(a, a))
+
+	b := a.Clone()
+	b.SynapseMap[SynapseGene{SourceID: 0, DestID: 0, Weight: 1}] = struct{}{}
+	assert.Equal(t, 1.0/float64(len(b.SynapseMap)), Compatibility(a, b))
+	assert.Equal(t, Compatibility(a, b), Compatibility(b, a))
+
+	assert.Equal(t, 1, Species([]DNA{a, a, b}, SpeciesThreshold))
+	assert.Equal(t, 2, Species([]DNA{a, b}, 0.1))
+}
+
+func TestPopulationMetrics(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 1).Build()
+	require.NoError(t, err)
+	dna := NetToDna(n)
+	m := NewPopulationMetrics("a", []float64{1, 3, math.Inf(1)}, []DNA{dna, dna, dna})
+	assert.Equal(t, 3, m.Size)
+	assert.Equal(t, 2, m.Finite)
+	assert.Equal(t, 1.0, m.Best)
+	assert.Equal(t, 2.0, m.Mean)
+	assert.Equal(t, 1.0, m.Std)
+	assert.Equal(t, 1, m.Species)
+	assert.Equal(t, float64(len(dna.Neurons)), m.Neurons)
+	assert.Equal(t, float64(len(dna.SynapseMap)), m.Synapses)
+}
+
+func TestPopulationMetricsNoFinite(t *testing.T) {
+	m := NewPopulationMetrics("a", []float64{math.Inf(1), math.NaN()}, nil)
+	assert.Equal(t, 2, m.Size)
+	assert.Equal(t, 0, m.Finite)
+	g := GenerationMetrics{Populations: []PopulationMetrics{m, {Finite: 1, Best: 3}}}
+	assert.Equal(t, 3.0, g.Best())
+	assert.True(t, math.IsInf(GenerationMetrics{Populations: []PopulationMetrics{m}}.Best(), 1))
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatCSV} {
+		var buf bytes.Buffer
+		r, err := NewMetricsRecorder(&buf, format)
+		require.NoError(t, err)
+
+		e := NewEvolution(func(dna DNA) (float64, error) { return float64(len(dna.SynapseMap)), nil })
+		e.PopSize = 10
+		e.Generations = 3
+		e.Mutate = r.Mutations.Wrap((*DNA).MutateAll)
+		e.Callback = func(e *Evolution) { require.NoError(t, r.RecordEvolution(e)) }
+		require.NoError(t, e.Minimize(func(rng *rand.Rand) DNA {
+			n, _ := NewBuilder().Size(2, 2, 1).Build()
+			return NetToDna(n)
+		}))
+
+		metrics, err := ReadMetrics(&buf)
+		require.NoError(t, err, format)
+		require.Len(t, metrics, 4, format)
+		for i, m := range metrics {
+			assert.Equal(t, i, m.Generation)
+			require.Len(t, m.Populations, 1)
+			assert.Equal(t, 10, m.Populations[0].Size)
+			assert.Greater(t, m.Populations[0].Synapses, 0.0)
+		}
+		assert.Equal(t, 0, metrics[0].Mutations.Mutations)
+		assert.Greater(t, metrics[1].Mutations.Mutations, 0)
+		assert.Equal(t, e.Best.Fitness, metrics[3].Best())
+	}
+
+	_, err := NewMetricsRecorder(&bytes.Buffer{}, "xml")
+	assert.Error(t, err)
+}
@@ -19,8 +19,12 @@ type Net struct {
 	out            []*neuron
 	hidden         []*neuron
 	signalID       int
+	ticks          int
+	dt             float64
+	stepOrder      []*neuron
 	activationFunc func(float64) float64
 	weightFunc     func() float64
+	tracer         Tracer
 }
 
 func (n *Net) InSize() int {
@@ -40,17 +44,24 @@ func newNet(b *Builder) *Net {
 	{ // set config
 		n.activationFunc = b.activationFunc
 		n.weightFunc = b.weightFunc
+		n.ticks = b.ticks
+		n.dt = b.dt
 	}
 
 	{ // init neurons
 		neurons := make([]*neuron, in+hidden+out, in+hidden+out)
 		for i := range neurons {
-			neurons[i] = &neuron{id: i, bias: defaultBiasFunc()}
+			neurons[i] = &neuron{id: i, bias: b.biasFunc()}
 			neurons[i].activationFunc = n.activationFunc
 			if i < in {
 				neurons[i].layer = inputLayer
 			} else if i < in+hidden {
 				neurons[i].layer = hiddenLayer
+				neurons[i].kind = byte(b.hiddenKind)
+				neurons[i].tau = b.tau
+				for g := 0; g < gateCount(b.hiddenKind); g++ {
+					neurons[i].gates = append(neurons[i].gates, n.weightFunc())
+				}
 			} else {
 				neurons[i].layer = outputLayer
 			}
@@ -82,6 +93,15 @@ func (n *Net) Eval(input []float64) (output []float64, err error) {
 		return nil, fmt.Errorf("Net not initialised, use the Builder")
 	}
 
+	if n.ticks > 0 {
+		for i := 0; i < n.ticks; i++ {
+			if output, err = n.Step(input); err != nil {
+				return nil, err
+			}
+		}
+		return output, nil
+	}
+
 	n.signalID += 1
 
 	// Set input
@@ -100,8 +120,24 @@ func (n *Net) Eval(input []float64) (output []float64, err error) {
 		output = append(output, s.v)
 	}
 
+	if n.tracer != nil {
+		for i, neur := range n.in {
+			neur.trace(input[i], input[i])
+		}
+		n.tracer.Done(input, output)
+	}
+
 	// reset net
 	for _, neur := range n.neuronStore {
+		switch {
+		case neur.calculated != nil:
+			neur.last = neur.calculated.v
+		case neur.layer == inputLayer && neur.memory != nil:
+			neur.last = neur.memory.v
+		default:
+			// not connected to an output
+			neur.last = 0
+		}
 		neur.calculated = nil
 	}
 
@@ -205,6 +241,12 @@ digraph G {
 }`, inputNeuronIDs, hiddenNeuronIDs, outNeuronIDs, synsStr)
 }
 
+// TimeStep sets the dt used to integrate CTRNN neurons, every Eval (or Step
+// in step mode) advances them by dt
+func (n *Net) TimeStep(dt float64) {
+	n.dt = dt
+}
+
 func sigmoid(x float64) float64 {
 	return (1/(1+math.Exp(x*(-1))) - 0.5) * 2
 }
@@ -236,5 +278,7 @@ func (n *Net) addNeuron(neur *neuron) error {
 		return fmt.Errorf("unknown layertype for neuron %d", neur.id)
 	}
 	n.neuronStore[neur.id] = neur
+	neur.net = n
+	n.stepOrder = nil
 	return nil
 }
@@ -39,6 +39,18 @@ func TestNewNet(t *testing.T) {
 	}
 }
 
+func TestBuilder_BiasFunc(t *testing.T) {
+	n, err := NewBuilder().Size(2, 3, 1).BiasFunc(func() float64 { return 0.25 }).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, neur := range n.neuronStore {
+		if neur.bias != 0.25 {
+			t.Errorf("neuron %d has bias %v, expected the BiasFunc's 0.25", neur.id, neur.bias)
+		}
+	}
+}
+
 func TestNeuronStore(t *testing.T) {
 	n, err := NewBuilder().
 		Build()
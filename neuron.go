@@ -1,20 +1,67 @@
 package net
 
+import (
+	"fmt"
+	"math"
+)
+
 const (
 	inputLayer = iota
 	hiddenLayer
 	outputLayer
 )
 
+// Neuron kinds, stored in NeuronGene.Kind
+const (
+	// KindStandard neurons activate instantly on their input
+	KindStandard = iota
+	// KindCTRNN neurons leakily integrate their input with time constant Tau
+	KindCTRNN
+	// KindGRU neurons are gated recurrent units, see gates.go
+	KindGRU
+	// KindLSTM neurons are long short-term memory cells, see gates.go
+	KindLSTM
+)
+
+var kindNames = map[int]string{
+	KindStandard: "standard",
+	KindCTRNN:    "ctrnn",
+	KindGRU:      "gru",
+	KindLSTM:     "lstm",
+}
+
+// KindName returns the name of a neuron kind, as used in flags and output
+func KindName(kind int) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind%d", kind)
+}
+
+const (
+	defaultTimeStep = 0.1
+	defaultTau      = 1.0
+)
+
 type neuron struct {
 	id               int
 	layer            byte
+	kind             byte
+	tau              float64
+	potential        float64 // integrated input of CTRNN neurons
+	gates            []float64
+	h, c             float64 // hidden and cell state of gated neurons
 	shouldSaveMemory bool
 	bias             float64
 	visited          bool
 	memory           *signal
 	calculated       *signal
+	state            float64 // activation of the previous step in step mode
+	next             float64
+	last             float64 // activation of the last Eval or Step
+	activation       byte    // index in activationFuncs
 	activationFunc   func(float64) float64
+	net              *Net
 
 	in  []*synapse // incoming connections
 	out []*synapse // outgoing connections
@@ -22,9 +69,13 @@ type neuron struct {
 
 func (n *neuron) DNA() *NeuronGene {
 	return &NeuronGene{
-		ID:    n.id,
-		Bias:  n.bias,
-		Layer: int(n.layer),
+		ID:         n.id,
+		Bias:       n.bias,
+		Layer:      int(n.layer),
+		Kind:       int(n.kind),
+		Tau:        n.tau,
+		Gates:      append([]float64(nil), n.gates...),
+		Activation: int(n.activation),
 	}
 }
 
@@ -57,10 +108,8 @@ func (n *neuron) eval(id int) signal {
 	}
 
 	//fmt.Println("synapses returned:", sum)
-	if n.activationFunc == nil {
-		n.activationFunc = sigmoid
-	}
-	sig := signal{v: n.activationFunc((sum + n.bias) * n.bias), id: id}
+	sig := signal{v: n.activate(sum), id: id}
+	n.trace(sum, sig.v)
 	if n.shouldSaveMemory {
 		n.memory = &sig
 		//		fmt.Println("savin mem for neur:", n.id, "mem:", sig)
@@ -70,3 +119,26 @@ func (n *neuron) eval(id int) signal {
 	//	fmt.Println("after sigmoid neuron:", n.id, "returns", sig)
 	return sig
 }
+
+// activate returns the neuron's output for the summed input
+func (n *neuron) activate(sum float64) float64 {
+	if n.activationFunc == nil {
+		n.activationFunc = sigmoid
+	}
+	switch n.kind {
+	case KindGRU:
+		return n.gru(sum + n.bias)
+	case KindLSTM:
+		return n.lstm(sum + n.bias)
+	case KindCTRNN:
+		// Euler step of tau * dy/dt = -y + sum
+		dt := defaultTimeStep
+		if n.net != nil && n.net.dt > 0 {
+			dt = n.net.dt
+		}
+		tau := math.Max(n.tau, dt)
+		n.potential += dt / tau * (sum - n.potential)
+		sum = n.potential
+	}
+	return n.activationFunc((sum + n.bias) * n.bias)
+}
@@ -0,0 +1,161 @@
+package net
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Novelty scores behaviours by how different they are from the behaviours
+// seen before, so evolution keeps exploring instead of converging on a
+// deceptive fitness optimum. The user describes the behaviour of a Net as a
+// vector with Behaviour, novelty is the mean distance to the K nearest
+// neighbours in the archive and the current population.
+//
+// Score and Add are safe for concurrent use, so Score can be called from an
+// eaopt Evaluate and Add from the GA's Callback. With Evolution set
+// Evolution.Novelty instead.
+type Novelty struct {
+	K int
+	// Behaviour describes what a net does, for example where a snake went
+	Behaviour func(*Net) ([]float64, error)
+	// Threshold is the novelty above which a behaviour is archived
+	Threshold float64
+	// MaxArchive caps the archive, the oldest behaviours are dropped first.
+	// 0 means no cap.
+	MaxArchive int
+	// Weight is the share of novelty in Combine's ranking, 0 ranks on
+	// fitness only and 1 on novelty only
+	Weight float64
+
+	mu      sync.RWMutex
+	archive [][]float64
+}
+
+// NewNovelty returns a Novelty with default settings
+func NewNovelty(behaviour func(*Net) ([]float64, error)) *Novelty {
+	return &Novelty{
+		K:          15,
+		Behaviour:  behaviour,
+		Threshold:  0,
+		MaxArchive: 1000,
+		Weight:     0.5,
+	}
+}
+
+// Score returns the novelty of behaviour compared to the archive and others
+func (nv *Novelty) Score(behaviour []float64, others [][]float64) float64 {
+	nv.mu.RLock()
+	defer nv.mu.RUnlock()
+
+	dists := make([]float64, 0, len(nv.archive)+len(others))
+	for _, b := range nv.archive {
+		dists = append(dists, distance(behaviour, b))
+	}
+	for _, b := range others {
+		dists = append(dists, distance(behaviour, b))
+	}
+	if len(dists) == 0 {
+		return math.Inf(1)
+	}
+	sort.Float64s(dists)
+	k := nv.K
+	if k < 1 || k > len(dists) {
+		k = len(dists)
+	}
+	var sum float64
+	for _, d := range dists[:k] {
+		sum += d
+	}
+	return sum / float64(k)
+}
+
+// Add archives the behaviour if its novelty is above the threshold
+func (nv *Novelty) Add(behaviour []float64, novelty float64) {
+	if novelty <= nv.Threshold {
+		return
+	}
+	nv.mu.Lock()
+	defer nv.mu.Unlock()
+	nv.archive = append(nv.archive, append([]float64(nil), behaviour...))
+	if nv.MaxArchive > 0 && len(nv.archive) > nv.MaxArchive {
+		nv.archive = nv.archive[len(nv.archive)-nv.MaxArchive:]
+	}
+}
+
+// Archive returns a copy of the archived behaviours
+func (nv *Novelty) Archive() [][]float64 {
+	nv.mu.RLock()
+	defer nv.mu.RUnlock()
+	archive := make([][]float64, len(nv.archive))
+	copy(archive, nv.archive)
+	return archive
+}
+
+// Combine scores every behaviour of a population against the archive and the
+// rest of the population, archives the novel ones and returns a score to
+// minimize that mixes the rank on fitness (lower is better) with the rank on
+// novelty (higher is better) by Weight.
+func (nv *Novelty) Combine(fitness []float64, behaviours [][]float64) []float64 {
+	novelty := make([]float64, len(behaviours))
+	for i, b := range behaviours {
+		others := make([][]float64, 0, len(behaviours)-1)
+		others = append(others, behaviours[:i]...)
+		others = append(others, behaviours[i+1:]...)
+		novelty[i] = nv.Score(b, others)
+	}
+	for i, b := range behaviours {
+		nv.Add(b, novelty[i])
+	}
+
+	negNovelty := make([]float64, len(novelty))
+	for i, v := range novelty {
+		negNovelty[i] = -v
+	}
+	fitRanks := centeredRanks(fitness)
+	novRanks := centeredRanks(negNovelty)
+	scores := make([]float64, len(fitness))
+	for i := range scores {
+		scores[i] = (1-nv.Weight)*fitRanks[i] + nv.Weight*novRanks[i]
+	}
+	return scores
+}
+
+// apply replaces the fitness of the individuals by their combined fitness and
+// novelty score
+func (nv *Novelty) apply(indis []Individual, workers int) error {
+	behaviours := make([][]float64, len(indis))
+	errs := make([]error, len(indis))
+	parallel(len(indis), workers, func(i int) {
+		n, err := DNAToNet(indis[i].DNA)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		behaviours[i], errs[i] = nv.Behaviour(n)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	fitness := make([]float64, len(indis))
+	for i := range indis {
+		fitness[i] = indis[i].Fitness
+	}
+	for i, score := range nv.Combine(fitness, behaviours) {
+		indis[i].Score = score
+	}
+	return nil
+}
+
+func distance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		if i < len(b) {
+			d := a[i] - b[i]
+			sum += d * d
+		}
+	}
+	return math.Sqrt(sum)
+}
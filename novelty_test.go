@@ -0,0 +1,70 @@
+package net
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNovelty_Score(t *testing.T) {
+	nv := NewNovelty(nil)
+	nv.K = 2
+	assert.True(t, math.IsInf(nv.Score([]float64{0, 0}, nil), 1))
+
+	others := [][]float64{{1, 0}, {0, 2}, {10, 10}}
+	assert.InDelta(t, 1.5, nv.Score([]float64{0, 0}, others), 1e-9)
+
+	nv.Add([]float64{0, 0.5}, 1)
+	assert.InDelta(t, 0.75, nv.Score([]float64{0, 0}, others), 1e-9)
+	// not novel enough to be archived
+	nv.Threshold = 2
+	nv.Add([]float64{5, 5}, 1)
+	assert.Len(t, nv.Archive(), 1)
+}
+
+func TestNovelty_MaxArchive(t *testing.T) {
+	nv := NewNovelty(nil)
+	nv.MaxArchive = 3
+	for i := 0; i < 5; i++ {
+		nv.Add([]float64{float64(i)}, 1)
+	}
+	assert.Equal(t, [][]float64{{2}, {3}, {4}}, nv.Archive())
+}
+
+func TestNovelty_Combine(t *testing.T) {
+	behaviours := [][]float64{{0}, {0.1}, {5}}
+	fitness := []float64{1, 2, 3}
+
+	nv := NewNovelty(nil)
+	nv.Weight = 0
+	assert.Equal(t, centeredRanks(fitness), nv.Combine(fitness, behaviours))
+
+	nv = NewNovelty(nil)
+	nv.Weight = 1
+	scores := nv.Combine(fitness, behaviours)
+	// the outlier is the most novel, so it scores lowest
+	assert.Less(t, scores[2], scores[0])
+	assert.Less(t, scores[2], scores[1])
+	assert.Len(t, nv.Archive(), 3)
+}
+
+func TestEvolution_Novelty(t *testing.T) {
+	behaviour := func(n *Net) ([]float64, error) {
+		return n.Eval([]float64{1, 0})
+	}
+	e := NewEvolution(testFitness)
+	e.PopSize = 20
+	e.Generations = 5
+	e.Novelty = NewNovelty(behaviour)
+	require.NoError(t, e.Minimize(newTestDNA))
+
+	assert.NotEmpty(t, e.Novelty.Archive())
+	for i := 1; i < len(e.Population); i++ {
+		assert.LessOrEqual(t, e.Population[i-1].Score, e.Population[i].Score)
+	}
+	for _, indi := range e.Population {
+		assert.LessOrEqual(t, e.Best.Fitness, indi.Fitness)
+	}
+}
@@ -0,0 +1,276 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// NSGA2 evolves DNA on several objectives at once, following Deb et al.'s
+// NSGA-II, so fitness and size don't have to be folded into one number with a
+// hand-tuned penalty. Every objective is minimized. The result is a Pareto
+// front: the individuals no other individual beats on every objective.
+type NSGA2 struct {
+	PopSize     int
+	Generations int
+	CrossRate   float64
+	MutRate     float64
+	// Workers is the amount of evaluations run in parallel, 0 uses GOMAXPROCS
+	Workers int
+	Seed    int64
+
+	// Objectives returns the objectives of the dna, every dna should return
+	// the same amount
+	Objectives func(DNA) ([]float64, error)
+	// Mutate defaults to DNA.MutateAll, Cross to DNA.Mate
+	Mutate func(*DNA, *rand.Rand)
+	Cross  func(DNA, DNA, *rand.Rand) DNA
+	// Callback is called after every generation, EarlyStop before every
+	// generation
+	Callback  func(*NSGA2)
+	EarlyStop func(*NSGA2) bool
+
+	// Population is sorted on rank, then from most to least crowding distance
+	Population []MultiIndividual
+	Generation int
+
+	rng *rand.Rand
+}
+
+// MultiIndividual is a DNA with its objectives. Rank 0 is the Pareto front,
+// rank 1 the front once rank 0 is removed and so on. Crowding is how isolated
+// the individual is within its front, the extremes have +Inf.
+type MultiIndividual struct {
+	DNA        DNA
+	Objectives []float64
+	Rank       int
+	Crowding   float64
+}
+
+// NewNSGA2 returns an NSGA2 with default settings
+func NewNSGA2(objectives func(DNA) ([]float64, error)) *NSGA2 {
+	return &NSGA2{
+		PopSize:     100,
+		Generations: 100,
+		CrossRate:   0.5,
+		MutRate:     1,
+		Seed:        1,
+		Objectives:  objectives,
+	}
+}
+
+// Complexity is the amount of neurons and synapses of the dna, an objective
+// to keep nets small
+func Complexity(dna DNA) float64 {
+	return float64(len(dna.Neurons) + len(dna.SynapseMap))
+}
+
+// Minimize runs the evolution, newDNA creates the initial population
+func (ns *NSGA2) Minimize(newDNA func(rng *rand.Rand) DNA) error {
+	if ns.Objectives == nil {
+		return fmt.Errorf("no objectives function set")
+	}
+	if ns.PopSize < 2 {
+		return fmt.Errorf("PopSize should be > 1")
+	}
+	if ns.Mutate == nil {
+		ns.Mutate = (*DNA).MutateAll
+	}
+	if ns.Cross == nil {
+		ns.Cross = func(a, b DNA, rng *rand.Rand) DNA { return a.Mate(b, rng) }
+	}
+	ns.rng = rand.New(rand.NewSource(ns.Seed))
+	ns.Generation = 0
+
+	pop := make([]MultiIndividual, ns.PopSize)
+	for i := range pop {
+		pop[i].DNA = newDNA(ns.rng)
+	}
+	if err := ns.evaluate(pop); err != nil {
+		return err
+	}
+	ns.Population = ns.survive(pop)
+	if ns.Callback != nil {
+		ns.Callback(ns)
+	}
+
+	for ns.Generation < ns.Generations {
+		if ns.EarlyStop != nil && ns.EarlyStop(ns) {
+			return nil
+		}
+		offspring := make([]MultiIndividual, ns.PopSize)
+		for i := range offspring {
+			child := ns.Population[ns.tournament()].DNA.Clone()
+			if ns.rng.Float64() < ns.CrossRate {
+				child = ns.Cross(child, ns.Population[ns.tournament()].DNA, ns.rng)
+			}
+			if ns.rng.Float64() < ns.MutRate {
+				ns.Mutate(&child, ns.rng)
+			}
+			offspring[i].DNA = child
+		}
+		if err := ns.evaluate(offspring); err != nil {
+			return err
+		}
+		ns.Population = ns.survive(append(ns.Population, offspring...))
+		ns.Generation++
+		if ns.Callback != nil {
+			ns.Callback(ns)
+		}
+	}
+	return nil
+}
+
+// Front returns the Pareto front of the population
+func (ns *NSGA2) Front() []MultiIndividual {
+	var front []MultiIndividual
+	for _, indi := range ns.Population {
+		if indi.Rank == 0 {
+			front = append(front, indi)
+		}
+	}
+	return front
+}
+
+// evaluate sets the objectives of the individuals using the worker pool
+func (ns *NSGA2) evaluate(indis []MultiIndividual) error {
+	errs := make([]error, len(indis))
+	parallel(len(indis), ns.Workers, func(i int) {
+		indis[i].Objectives, errs[i] = ns.Objectives(indis[i].DNA)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return err
+		}
+		if len(indis[i].Objectives) != len(indis[0].Objectives) {
+			return fmt.Errorf("got %d objectives, expected %d", len(indis[i].Objectives), len(indis[0].Objectives))
+		}
+		for j, v := range indis[i].Objectives {
+			if math.IsNaN(v) {
+				indis[i].Objectives[j] = math.Inf(1)
+			}
+		}
+	}
+	return nil
+}
+
+// survive keeps the best PopSize individuals, front by front, and breaks the
+// tie in the last front that fits partially on crowding distance
+func (ns *NSGA2) survive(pop []MultiIndividual) []MultiIndividual {
+	objs := make([][]float64, len(pop))
+	for i := range pop {
+		objs[i] = pop[i].Objectives
+	}
+	next := make([]MultiIndividual, 0, ns.PopSize)
+	for rank, front := range NonDominatedSort(objs) {
+		crowding := CrowdingDistance(objs, front)
+		sorted := make([]MultiIndividual, len(front))
+		for i, idx := range front {
+			sorted[i] = pop[idx]
+			sorted[i].Rank = rank
+			sorted[i].Crowding = crowding[i]
+		}
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Crowding > sorted[j].Crowding })
+		if len(next)+len(sorted) > ns.PopSize {
+			sorted = sorted[:ns.PopSize-len(next)]
+		}
+		next = append(next, sorted...)
+		if len(next) == ns.PopSize {
+			break
+		}
+	}
+	return next
+}
+
+// tournament is the crowded binary tournament, the population is sorted so
+// the lowest index wins
+func (ns *NSGA2) tournament() int {
+	a, b := ns.rng.Intn(len(ns.Population)), ns.rng.Intn(len(ns.Population))
+	if b < a {
+		return b
+	}
+	return a
+}
+
+// Dominates reports whether a is at least as good as b on every objective and
+// better on at least one
+func Dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+		if a[i] < b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// NonDominatedSort splits the objective vectors into fronts of indices, the
+// first front is dominated by none, the second only by the first and so on
+func NonDominatedSort(objs [][]float64) [][]int {
+	dominatedBy := make([]int, len(objs))
+	dominates := make([][]int, len(objs))
+	var front []int
+	for i := range objs {
+		for j := range objs {
+			if Dominates(objs[i], objs[j]) {
+				dominates[i] = append(dominates[i], j)
+			} else if Dominates(objs[j], objs[i]) {
+				dominatedBy[i]++
+			}
+		}
+		if dominatedBy[i] == 0 {
+			front = append(front, i)
+		}
+	}
+	var fronts [][]int
+	for len(front) > 0 {
+		fronts = append(fronts, front)
+		var next []int
+		for _, i := range front {
+			for _, j := range dominates[i] {
+				dominatedBy[j]--
+				if dominatedBy[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		sort.Ints(next)
+		front = next
+	}
+	return fronts
+}
+
+// CrowdingDistance returns for every index of the front the sum over the
+// objectives of the normalized distance between its neighbours
+func CrowdingDistance(objs [][]float64, front []int) []float64 {
+	dist := make([]float64, len(front))
+	if len(front) == 0 {
+		return dist
+	}
+	order := make([]int, len(front))
+	for m := range objs[front[0]] {
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return objs[front[order[i]]][m] < objs[front[order[j]]][m]
+		})
+		min := objs[front[order[0]]][m]
+		max := objs[front[order[len(order)-1]]][m]
+		dist[order[0]] = math.Inf(1)
+		dist[order[len(order)-1]] = math.Inf(1)
+		if max == min || math.IsInf(max-min, 0) {
+			continue
+		}
+		for i := 1; i < len(order)-1; i++ {
+			prev := objs[front[order[i-1]]][m]
+			next := objs[front[order[i+1]]][m]
+			dist[order[i]] += (next - prev) / (max - min)
+		}
+	}
+	return dist
+}
@@ -0,0 +1,60 @@
+package net
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDominates(t *testing.T) {
+	assert.True(t, Dominates([]float64{1, 2}, []float64{1, 3}))
+	assert.False(t, Dominates([]float64{1, 2}, []float64{1, 2}))
+	assert.False(t, Dominates([]float64{0, 3}, []float64{1, 2}))
+}
+
+func TestNonDominatedSort(t *testing.T) {
+	objs := [][]float64{
+		{1, 5},
+		{2, 2},
+		{5, 1},
+		{3, 3},
+		{4, 4},
+		{2, 2},
+	}
+	assert.Equal(t, [][]int{{0, 1, 2, 5}, {3}, {4}}, NonDominatedSort(objs))
+}
+
+func TestCrowdingDistance(t *testing.T) {
+	objs := [][]float64{{0, 4}, {1, 3}, {3, 1}, {4, 0}}
+	dist := CrowdingDistance(objs, []int{0, 1, 2, 3})
+	assert.True(t, math.IsInf(dist[0], 1))
+	assert.True(t, math.IsInf(dist[3], 1))
+	assert.InDelta(t, 3.0/4+3.0/4, dist[1], 1e-9)
+	assert.InDelta(t, dist[1], dist[2], 1e-9)
+}
+
+func TestNSGA2(t *testing.T) {
+	objectives := func(dna DNA) ([]float64, error) {
+		fit, err := testFitness(dna)
+		return []float64{fit, Complexity(dna)}, err
+	}
+	ns := NewNSGA2(objectives)
+	ns.PopSize = 20
+	ns.Generations = 10
+	require.NoError(t, ns.Minimize(newTestDNA))
+	assert.Equal(t, 10, ns.Generation)
+	assert.Len(t, ns.Population, 20)
+
+	front := ns.Front()
+	require.NotEmpty(t, front)
+	for _, a := range front {
+		for _, b := range ns.Population {
+			assert.False(t, Dominates(b.Objectives, a.Objectives))
+		}
+	}
+	for i := 1; i < len(ns.Population); i++ {
+		assert.LessOrEqual(t, ns.Population[i-1].Rank, ns.Population[i].Rank)
+	}
+}
@@ -0,0 +1,56 @@
+package net
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Params returns the net's weights and biases as one vector, for optimizers
+// that work on fixed topologies. The order is stable: neurons by id, each
+// non-input neuron's bias followed by the weights of its incoming synapses by
+// source id.
+func (n *Net) Params() []float64 {
+	var params []float64
+	for _, neur := range n.order() {
+		if neur.layer == inputLayer {
+			continue
+		}
+		params = append(params, neur.bias)
+		for _, syn := range neur.sortedIn() {
+			params = append(params, syn.weight)
+		}
+	}
+	return params
+}
+
+// SetParams sets the net's weights and biases from a vector in the order of
+// Params
+func (n *Net) SetParams(params []float64) error {
+	var i int
+	for _, neur := range n.order() {
+		if neur.layer == inputLayer {
+			continue
+		}
+		syns := neur.sortedIn()
+		if i+1+len(syns) > len(params) {
+			return fmt.Errorf("got %d params, net has more", len(params))
+		}
+		neur.bias = params[i]
+		i++
+		for _, syn := range syns {
+			syn.weight = params[i]
+			i++
+		}
+	}
+	if i != len(params) {
+		return fmt.Errorf("got %d params, net has %d", len(params), i)
+	}
+	return nil
+}
+
+func (n *neuron) sortedIn() []*synapse {
+	syns := make([]*synapse, len(n.in))
+	copy(syns, n.in)
+	sort.SliceStable(syns, func(i, j int) bool { return syns[i].source.id < syns[j].source.id })
+	return syns
+}
@@ -0,0 +1,65 @@
+package net
+
+import "math"
+
+// Prune returns dna without the parts that can't change the output: synapses
+// into input neurons, which are ignored, and hidden neurons that don't lead to
+// an output, with their synapses. Synapses with an absolute weight of at most
+// threshold are removed first, so a threshold of 0 keeps the output the same
+// and a larger one trades accuracy for size.
+func (dna DNA) Prune(threshold float64) DNA {
+	layers := make(map[int]int, len(dna.Neurons))
+	for _, ng := range dna.Neurons {
+		layers[ng.ID] = ng.Layer
+	}
+	// ids dna doesn't have are hidden, like in DNAToNet
+	layer := func(id int) int {
+		if l, ok := layers[id]; ok {
+			return l
+		}
+		return hiddenLayer
+	}
+
+	var kept []SynapseGene
+	into := make(map[int][]SynapseGene)
+	for _, syn := range sortedSynapses(dna.SynapseMap) {
+		if layer(syn.DestID) == inputLayer || math.Abs(syn.Weight) <= threshold {
+			continue
+		}
+		kept = append(kept, syn)
+		into[syn.DestID] = append(into[syn.DestID], syn)
+	}
+
+	// walk back from the outputs
+	used := make(map[int]bool)
+	var stack []int
+	for _, ng := range dna.Neurons {
+		if ng.Layer == outputLayer {
+			used[ng.ID] = true
+			stack = append(stack, ng.ID)
+		}
+	}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, syn := range into[id] {
+			if !used[syn.SourceID] {
+				used[syn.SourceID] = true
+				stack = append(stack, syn.SourceID)
+			}
+		}
+	}
+
+	pruned := DNA{SynapseMap: make(map[SynapseGene]struct{}, len(kept))}
+	for _, ng := range dna.Clone().Neurons {
+		if ng.Layer != hiddenLayer || used[ng.ID] {
+			pruned.Neurons = append(pruned.Neurons, ng)
+		}
+	}
+	for _, syn := range kept {
+		if used[syn.DestID] {
+			pruned.SynapseMap[syn] = struct{}{}
+		}
+	}
+	return pruned
+}
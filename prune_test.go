@@ -0,0 +1,45 @@
+package net
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 1).Build()
+	require.NoError(t, err)
+	dna := NetToDna(n)
+	// a dead end, a synapse into an input and a zero weight
+	dna.Neurons = append(dna.Neurons, &NeuronGene{ID: 100, Layer: hiddenLayer})
+	dna.SynapseMap[SynapseGene{SourceID: 0, DestID: 100, Weight: 1}] = struct{}{}
+	dna.SynapseMap[SynapseGene{SourceID: 100, DestID: 101, Weight: 1}] = struct{}{}
+	dna.SynapseMap[SynapseGene{SourceID: 2, DestID: 0, Weight: 1}] = struct{}{}
+	dna.SynapseMap[SynapseGene{SourceID: 1, DestID: 4, Weight: 0}] = struct{}{}
+
+	pruned := dna.Prune(0)
+	assert.Len(t, pruned.Neurons, len(dna.Neurons)-1)
+	assert.Len(t, pruned.SynapseMap, len(dna.SynapseMap)-4)
+	require.NoError(t, pruned.Validate())
+
+	before, err := DNAToNet(dna)
+	require.NoError(t, err)
+	after, err := DNAToNet(pruned)
+	require.NoError(t, err)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		in := []float64{rng.Float64(), rng.Float64()}
+		want, err := before.Eval(in)
+		require.NoError(t, err)
+		got, err := after.Eval(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	// a threshold above every weight leaves the inputs and outputs
+	empty := dna.Prune(1e9)
+	assert.Empty(t, empty.SynapseMap)
+	assert.Len(t, empty.Neurons, 3)
+}
@@ -0,0 +1,509 @@
+package net
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	// lutRange is the pre-activation range covered by the activation tables,
+	// values outside of it saturate
+	lutRange = 8
+	lutSize  = 255
+	lutZero  = lutSize / 2
+)
+
+var quantizedMagic = [4]byte{'N', 'E', 'T', 'Q'}
+
+// QuantizedNet is a Net with its weights and biases quantized to int8. Eval
+// only uses integer arithmetic between quantizing the input and dequantizing
+// the output, EvalInt uses integers only.
+type QuantizedNet struct {
+	perNeuron bool
+	neurons   []qNeuron // in evaluation order
+	inputs    []int32   // index in neurons of each input
+	inScales  []float32 // real value of one step of each input
+	outputs   []int32   // index in neurons of each output
+	tables    []qTable
+	state     []int8
+}
+
+type qNeuron struct {
+	id    int32
+	input bool
+	// sources index neurons, loop marks sources that are read from the
+	// previous Eval, like Net does for recurrent connections
+	sources []int32
+	loop    []bool
+	weights []int8
+	// pre-activation is (acc + bias) * biasQ, scaled to a table index by
+	// mult / 2^shift
+	bias  int32
+	biasQ int8
+	mult  int32
+	shift uint8
+	table uint16
+}
+
+// qTable maps a table index to an activation value, scale is the real value
+// of one activation step
+type qTable struct {
+	scale  float32
+	values [lutSize]int8
+}
+
+// DeviationReport compares the outputs of a QuantizedNet with those of the Net
+// it was made from
+type DeviationReport struct {
+	Samples       int
+	MaxDeviation  float64
+	MeanDeviation float64
+	PerOutput     []float64 // max deviation per output
+}
+
+// Quantize converts the net's weights and biases to int8. With perNeuron each
+// neuron gets its own weight and bias scale, otherwise one scale is used for
+// the whole net. The samples are used to calibrate the input scales, without
+// them inputs are expected to lie in [-1, 1].
+func Quantize(n *Net, perNeuron bool, samples [][]float64) (*QuantizedNet, error) {
+	if n == nil {
+		return nil, fmt.Errorf("Net not initialised, use the Builder")
+	}
+	q := &QuantizedNet{perNeuron: perNeuron}
+
+	order, loops := evalOrder(n)
+	index := make(map[*neuron]int32, len(order))
+	for i, neur := range order {
+		index[neur] = int32(i)
+	}
+
+	{ // calibrate inputs
+		for i, neur := range n.in {
+			var max float64
+			for _, sample := range samples {
+				if i < len(sample) && math.Abs(sample[i]) > max {
+					max = math.Abs(sample[i])
+				}
+			}
+			if max == 0 {
+				max = 1
+			}
+			idx, ok := index[neur]
+			if !ok {
+				// input is not connected to any output
+				idx = int32(len(order))
+				order = append(order, neur)
+				index[neur] = idx
+			}
+			q.inputs = append(q.inputs, idx)
+			q.inScales = append(q.inScales, float32(max/127))
+		}
+	}
+
+	// real value of one step of each neuron's output
+	scales := make([]float64, len(order))
+	for i, idx := range q.inputs {
+		scales[idx] = float64(q.inScales[i])
+	}
+
+	for i, neur := range order {
+		q.neurons = append(q.neurons, qNeuron{
+			id:    int32(neur.id),
+			input: neur.layer == inputLayer,
+		})
+		if neur.layer == inputLayer {
+			continue
+		}
+		if neur.kind != KindStandard {
+			return nil, fmt.Errorf("neuron %d can't be quantized, only standard neurons can", neur.id)
+		}
+		table := q.table(neur.activationFunc)
+		scales[i] = float64(q.tables[table].scale)
+		q.neurons[i].table = table
+	}
+
+	// weights with the scale of their source folded in, all scales are known
+	// by now, loop synapses come from neurons later in the order
+	folded := make([][]float64, len(order))
+	var maxWeight, maxBias float64
+	for i, neur := range order {
+		if neur.layer == inputLayer {
+			continue
+		}
+		for _, syn := range neur.in {
+			src := index[syn.source]
+			q.neurons[i].sources = append(q.neurons[i].sources, src)
+			q.neurons[i].loop = append(q.neurons[i].loop, loops[syn])
+			w := syn.weight * scales[src]
+			folded[i] = append(folded[i], w)
+			maxWeight = math.Max(maxWeight, math.Abs(w))
+		}
+		maxBias = math.Max(maxBias, math.Abs(neur.bias))
+	}
+
+	for i, neur := range order {
+		if neur.layer == inputLayer {
+			continue
+		}
+		wScale, bScale := maxWeight/127, maxBias/127
+		if perNeuron {
+			wScale, bScale = 0, math.Abs(neur.bias)/127
+			for _, w := range folded[i] {
+				wScale = math.Max(wScale, math.Abs(w)/127)
+			}
+		}
+		if wScale == 0 {
+			wScale = 1
+		}
+		if bScale == 0 {
+			bScale = 1
+		}
+		qn := &q.neurons[i]
+		for _, w := range folded[i] {
+			qn.weights = append(qn.weights, int8(math.Round(w/wScale)))
+		}
+		qn.bias = int32(math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(neur.bias/wScale))))
+		qn.biasQ = int8(math.Round(neur.bias / bScale))
+		qn.mult, qn.shift = fixedPoint(wScale * bScale * lutZero / lutRange)
+	}
+
+	for _, neur := range n.out {
+		q.outputs = append(q.outputs, index[neur])
+	}
+	q.state = make([]int8, len(q.neurons))
+	return q, nil
+}
+
+// evalOrder returns the neurons reachable from the output in the order Net
+// evaluates them, and the synapses that close a loop
+func evalOrder(n *Net) (order []*neuron, loops map[*synapse]bool) {
+	loops = make(map[*synapse]bool)
+	done := make(map[*neuron]bool)
+	onPath := make(map[*neuron]bool)
+	var visit func(neur *neuron)
+	visit = func(neur *neuron) {
+		onPath[neur] = true
+		for _, syn := range neur.in {
+			if onPath[syn.source] {
+				loops[syn] = true
+				continue
+			}
+			if !done[syn.source] {
+				visit(syn.source)
+			}
+		}
+		onPath[neur] = false
+		done[neur] = true
+		order = append(order, neur)
+	}
+	for _, neur := range n.out {
+		if !done[neur] {
+			visit(neur)
+		}
+	}
+	return order, loops
+}
+
+// table returns the index of the activation table for f, identical tables
+// are shared
+func (q *QuantizedNet) table(f func(float64) float64) uint16 {
+	if f == nil {
+		f = sigmoid
+	}
+	var real [lutSize]float64
+	var max float64
+	for i := range real {
+		real[i] = f(float64(i-lutZero) * lutRange / lutZero)
+		max = math.Max(max, math.Abs(real[i]))
+	}
+	if max == 0 {
+		max = 1
+	}
+	t := qTable{scale: float32(max / 127)}
+	for i, v := range real {
+		t.values[i] = int8(math.Round(v / float64(t.scale)))
+	}
+	for i := range q.tables {
+		if q.tables[i] == t {
+			return uint16(i)
+		}
+	}
+	q.tables = append(q.tables, t)
+	return uint16(len(q.tables) - 1)
+}
+
+// fixedPoint returns m and shift so that f ≈ m / 2^shift with m in [2^30, 2^31)
+func fixedPoint(f float64) (int32, uint8) {
+	if f <= 0 {
+		return 0, 0
+	}
+	frac, exp := math.Frexp(f)
+	shift := 31 - exp
+	m := int64(math.Round(frac * (1 << 31)))
+	if m == 1<<31 {
+		m /= 2
+		shift--
+	}
+	for shift > 62 {
+		m >>= 1
+		shift--
+	}
+	if shift < 0 {
+		// pre-activation saturates no matter what
+		return math.MaxInt32, 0
+	}
+	return int32(m), uint8(shift)
+}
+
+func (q *QuantizedNet) InSize() int {
+	return len(q.inputs)
+}
+
+func (q *QuantizedNet) OutSize() int {
+	return len(q.outputs)
+}
+
+// Eval quantizes the input, sends it through the network and returns the
+// dequantized output
+func (q *QuantizedNet) Eval(input []float64) ([]float64, error) {
+	if len(input) != len(q.inputs) {
+		return nil, fmt.Errorf("input size %d, expected %d", len(input), len(q.inputs))
+	}
+	in := make([]int8, len(input))
+	for i, x := range input {
+		in[i] = clampInt8(math.Round(x / float64(q.inScales[i])))
+	}
+	out, err := q.EvalInt(in)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]float64, len(out))
+	for i, v := range out {
+		scale := q.tables[q.neurons[q.outputs[i]].table].scale
+		output[i] = float64(v) * float64(scale)
+	}
+	return output, nil
+}
+
+// EvalInt sends quantized input through the network using integer arithmetic
+// only
+func (q *QuantizedNet) EvalInt(input []int8) ([]int8, error) {
+	if len(input) != len(q.inputs) {
+		return nil, fmt.Errorf("input size %d, expected %d", len(input), len(q.inputs))
+	}
+	values := make([]int8, len(q.neurons))
+	for i, idx := range q.inputs {
+		values[idx] = input[i]
+	}
+	for i := range q.neurons {
+		neur := &q.neurons[i]
+		if neur.input {
+			continue
+		}
+		var acc int64
+		for j, src := range neur.sources {
+			v := values[src]
+			if neur.loop[j] {
+				v = q.state[src]
+			}
+			acc += int64(neur.weights[j]) * int64(v)
+		}
+		pre := (acc + int64(neur.bias)) * int64(neur.biasQ)
+		if pre > math.MaxInt32 {
+			pre = math.MaxInt32
+		} else if pre < math.MinInt32 {
+			pre = math.MinInt32
+		}
+		idx := pre * int64(neur.mult)
+		if neur.shift > 0 {
+			idx = (idx + 1<<(neur.shift-1)) >> neur.shift
+		}
+		if idx > lutZero {
+			idx = lutZero
+		} else if idx < -lutZero {
+			idx = -lutZero
+		}
+		values[i] = q.tables[neur.table].values[idx+lutZero]
+	}
+	copy(q.state, values)
+
+	output := make([]int8, len(q.outputs))
+	for i, idx := range q.outputs {
+		output[i] = values[idx]
+	}
+	return output, nil
+}
+
+func clampInt8(x float64) int8 {
+	if x > 127 {
+		return 127
+	}
+	if x < -127 {
+		return -127
+	}
+	return int8(x)
+}
+
+// Deviation evaluates the samples on both the net and the quantized net and
+// reports how far the quantized outputs are off
+func (q *QuantizedNet) Deviation(n *Net, samples [][]float64) (DeviationReport, error) {
+	report := DeviationReport{PerOutput: make([]float64, len(q.outputs))}
+	var sum float64
+	for _, sample := range samples {
+		out, err := n.Eval(sample)
+		if err != nil {
+			return report, err
+		}
+		qout, err := q.Eval(sample)
+		if err != nil {
+			return report, err
+		}
+		for i := range qout {
+			dev := math.Abs(out[i] - qout[i])
+			sum += dev
+			report.PerOutput[i] = math.Max(report.PerOutput[i], dev)
+			report.MaxDeviation = math.Max(report.MaxDeviation, dev)
+		}
+		report.Samples++
+	}
+	if report.Samples > 0 && len(q.outputs) > 0 {
+		report.MeanDeviation = sum / float64(report.Samples*len(q.outputs))
+	}
+	return report, nil
+}
+
+// MarshalBinary encodes the quantized net in a compact little endian format
+func (q *QuantizedNet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	w := func(v interface{}) {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	w(quantizedMagic)
+	w(q.perNeuron)
+	w(uint32(len(q.tables)))
+	for _, t := range q.tables {
+		w(t.scale)
+		w(t.values)
+	}
+	w(uint32(len(q.inputs)))
+	w(q.inputs)
+	w(q.inScales)
+	w(uint32(len(q.outputs)))
+	w(q.outputs)
+	w(uint32(len(q.neurons)))
+	for _, neur := range q.neurons {
+		w(neur.id)
+		w(neur.input)
+		if neur.input {
+			continue
+		}
+		w(uint32(len(neur.sources)))
+		w(neur.sources)
+		w(neur.loop)
+		w(neur.weights)
+		w(neur.bias)
+		w(neur.biasQ)
+		w(neur.mult)
+		w(neur.shift)
+		w(neur.table)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a quantized net encoded by MarshalBinary
+func (q *QuantizedNet) UnmarshalBinary(data []byte) error {
+	buf := bytes.NewReader(data)
+	var err error
+	r := func(v interface{}) {
+		if err == nil {
+			err = binary.Read(buf, binary.LittleEndian, v)
+		}
+	}
+	length := func() int {
+		var l uint32
+		r(&l)
+		if err == nil && int(l) > len(data) {
+			err = fmt.Errorf("invalid length %d", l)
+		}
+		return int(l)
+	}
+
+	var magic [4]byte
+	r(&magic)
+	if err == nil && magic != quantizedMagic {
+		return fmt.Errorf("not a quantized net")
+	}
+	var dec QuantizedNet
+	r(&dec.perNeuron)
+	dec.tables = make([]qTable, length())
+	for i := range dec.tables {
+		r(&dec.tables[i].scale)
+		r(&dec.tables[i].values)
+	}
+	dec.inputs = make([]int32, length())
+	r(dec.inputs)
+	dec.inScales = make([]float32, len(dec.inputs))
+	r(dec.inScales)
+	dec.outputs = make([]int32, length())
+	r(dec.outputs)
+	dec.neurons = make([]qNeuron, length())
+	for i := range dec.neurons {
+		neur := &dec.neurons[i]
+		r(&neur.id)
+		r(&neur.input)
+		if neur.input || err != nil {
+			continue
+		}
+		l := length()
+		neur.sources = make([]int32, l)
+		neur.loop = make([]bool, l)
+		neur.weights = make([]int8, l)
+		r(neur.sources)
+		r(neur.loop)
+		r(neur.weights)
+		r(&neur.bias)
+		r(&neur.biasQ)
+		r(&neur.mult)
+		r(&neur.shift)
+		r(&neur.table)
+	}
+	if err != nil {
+		return err
+	}
+	if err := dec.validate(); err != nil {
+		return err
+	}
+	dec.state = make([]int8, len(dec.neurons))
+	*q = dec
+	return nil
+}
+
+func (q *QuantizedNet) validate() error {
+	inRange := func(idx []int32) bool {
+		for _, i := range idx {
+			if i < 0 || int(i) >= len(q.neurons) {
+				return false
+			}
+		}
+		return true
+	}
+	if !inRange(q.inputs) || !inRange(q.outputs) {
+		return fmt.Errorf("neuron index out of range")
+	}
+	for _, neur := range q.neurons {
+		if !inRange(neur.sources) {
+			return fmt.Errorf("neuron %d has a source out of range", neur.id)
+		}
+		if !neur.input && int(neur.table) >= len(q.tables) {
+			return fmt.Errorf("neuron %d uses unknown table %d", neur.id, neur.table)
+		}
+	}
+	for _, idx := range q.outputs {
+		if q.neurons[idx].input {
+			return fmt.Errorf("output neuron %d is an input", q.neurons[idx].id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package net
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func samples(rng *rand.Rand, n, size int) [][]float64 {
+	var s [][]float64
+	for i := 0; i < n; i++ {
+		var sample []float64
+		for j := 0; j < size; j++ {
+			sample = append(sample, rng.Float64()*4-2)
+		}
+		s = append(s, sample)
+	}
+	return s
+}
+
+func TestQuantize(t *testing.T) {
+	n, err := NewBuilder().Size(4, 10, 3).Build()
+	require.NoError(t, err)
+	set := samples(rand.New(rand.NewSource(1)), 100, 4)
+
+	for _, perNeuron := range []bool{false, true} {
+		q, err := Quantize(n, perNeuron, set)
+		require.NoError(t, err)
+		assert.Equal(t, 4, q.InSize())
+		assert.Equal(t, 3, q.OutSize())
+
+		report, err := q.Deviation(n, set)
+		require.NoError(t, err)
+		assert.Equal(t, 100, report.Samples)
+		assert.Len(t, report.PerOutput, 3)
+		assert.Less(t, report.MaxDeviation, 0.1)
+		assert.LessOrEqual(t, report.MeanDeviation, report.MaxDeviation)
+	}
+}
+
+func TestQuantizedNet_MarshalBinary(t *testing.T) {
+	n, err := NewBuilder().Size(2, 5, 2).Build()
+	require.NoError(t, err)
+	q, err := Quantize(n, true, nil)
+	require.NoError(t, err)
+
+	data, err := q.MarshalBinary()
+	require.NoError(t, err)
+	var q2 QuantizedNet
+	require.NoError(t, q2.UnmarshalBinary(data))
+
+	for _, input := range [][]float64{{0, 0}, {1, -1}, {0.5, 0.25}} {
+		out, err := q.Eval(input)
+		require.NoError(t, err)
+		out2, err := q2.Eval(input)
+		require.NoError(t, err)
+		assert.Equal(t, out, out2)
+	}
+
+	assert.Error(t, q2.UnmarshalBinary(data[:len(data)-1]))
+	assert.Error(t, q2.UnmarshalBinary([]byte("nope")))
+}
+
+func TestQuantize_loop(t *testing.T) {
+	build := func() *Net {
+		rng := rand.New(rand.NewSource(1))
+		r := func() float64 { return rng.Float64()*2 - 1 }
+		n, err := NewBuilder().Size(1, 2, 1).WeightFunc(r).BiasFunc(r).Build()
+		require.NoError(t, err)
+		var hidden []int
+		for _, neur := range n.neuronStore {
+			if neur.layer == hiddenLayer {
+				hidden = append(hidden, neur.id)
+			}
+		}
+		sort.Ints(hidden)
+		// a loop between the hidden neurons, the synapse that closes it comes
+		// from the neuron evaluated last
+		n.addSynapse(hidden[0], hidden[1], 0.9)
+		n.addSynapse(hidden[1], hidden[0], 0.9)
+		return n
+	}
+	n := build()
+	q, err := Quantize(n, true, nil)
+	require.NoError(t, err)
+	loops := 0
+	for _, neur := range q.neurons {
+		for j, loop := range neur.loop {
+			if loop {
+				loops++
+				assert.NotZero(t, neur.weights[j])
+			}
+		}
+	}
+	assert.Equal(t, 1, loops)
+
+	// the recurrent state follows the float net
+	ref := build()
+	for _, x := range []float64{1, 0, 0, -1, 0.5, 0} {
+		out, err := ref.Eval([]float64{x})
+		require.NoError(t, err)
+		qout, err := q.Eval([]float64{x})
+		require.NoError(t, err)
+		assert.InDelta(t, out[0], qout[0], 0.05)
+	}
+}
+
+func TestQuantize_largeBias(t *testing.T) {
+	n, err := NewBuilder().Size(1, 1, 1).Build()
+	require.NoError(t, err)
+	n.out[0].bias = 1e12
+	q, err := Quantize(n, true, nil)
+	require.NoError(t, err)
+	for _, neur := range q.neurons {
+		if neur.id == int32(n.out[0].id) {
+			assert.Positive(t, neur.bias)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package net
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// SaliencyEpsilon is the step of the finite differences Sensitivity uses
+const SaliencyEpsilon = 1e-4
+
+// neuronState is what a neuron keeps between evaluations
+type neuronState struct {
+	potential, h, c   float64
+	state, next, last float64
+	memory            *signal
+	shouldSaveMemory  bool
+}
+
+// netState is a snapshot of everything Eval and Step change, so analyses can
+// evaluate a net without disturbing it
+type netState struct {
+	signalID int
+	neurons  map[*neuron]neuronState
+}
+
+func (n *Net) snapshot() netState {
+	s := netState{signalID: n.signalID, neurons: make(map[*neuron]neuronState, len(n.neuronStore))}
+	for _, neur := range n.neuronStore {
+		// signals are replaced, never changed, so the pointer is enough
+		s.neurons[neur] = neuronState{
+			potential:        neur.potential,
+			h:                neur.h,
+			c:                neur.c,
+			state:            neur.state,
+			next:             neur.next,
+			last:             neur.last,
+			memory:           neur.memory,
+			shouldSaveMemory: neur.shouldSaveMemory,
+		}
+	}
+	return s
+}
+
+func (n *Net) restore(s netState) {
+	n.signalID = s.signalID
+	for neur, ns := range s.neurons {
+		neur.potential = ns.potential
+		neur.h, neur.c = ns.h, ns.c
+		neur.state, neur.next, neur.last = ns.state, ns.next, ns.last
+		neur.memory = ns.memory
+		neur.shouldSaveMemory = ns.shouldSaveMemory
+	}
+}
+
+// analyse runs f on n without tracing and puts n back in the state it was in
+// afterwards
+func (n *Net) analyse(f func() error) error {
+	if n == nil {
+		return fmt.Errorf("Net not initialised, use the Builder")
+	}
+	tracer := n.tracer
+	n.tracer = nil
+	s := n.snapshot()
+	defer func() {
+		n.restore(s)
+		n.tracer = tracer
+	}()
+	return f()
+}
+
+// evalFrom evaluates input starting from state s
+func (n *Net) evalFrom(s netState, input []float64) ([]float64, error) {
+	n.restore(s)
+	if len(input) != len(n.in) {
+		return nil, fmt.Errorf("input size %d, expected %d", len(input), len(n.in))
+	}
+	return n.Eval(input)
+}
+
+func checkEpsilon(eps float64) error {
+	if !(eps > 0) {
+		return fmt.Errorf("eps should be > 0, got %g", eps)
+	}
+	return nil
+}
+
+// saliency returns the central difference of every output to every input,
+// evaluated from state s
+func (n *Net) saliency(s netState, input []float64, eps float64) ([][]float64, error) {
+	in := append([]float64(nil), input...)
+	grad := make([][]float64, len(in))
+	for i := range in {
+		in[i] = input[i] + eps
+		up, err := n.evalFrom(s, in)
+		if err != nil {
+			return nil, err
+		}
+		in[i] = input[i] - eps
+		down, err := n.evalFrom(s, in)
+		if err != nil {
+			return nil, err
+		}
+		in[i] = input[i]
+		grad[i] = make([]float64, len(up))
+		for j := range up {
+			grad[i][j] = (up[j] - down[j]) / (2 * eps)
+		}
+	}
+	return grad, nil
+}
+
+// Saliency returns how much every output changes with every input around
+// input, saliency[i][j] is the derivative of output j to input i, estimated
+// with central differences of step eps. Recurrent state is taken into account
+// as it is, n is left unchanged.
+func Saliency(n *Net, input []float64, eps float64) (saliency [][]float64, err error) {
+	if err := checkEpsilon(eps); err != nil {
+		return nil, err
+	}
+	err = n.analyse(func() error {
+		saliency, err = n.saliency(n.snapshot(), input, eps)
+		return err
+	})
+	return saliency, err
+}
+
+// Importance returns the mean absolute saliency of every input over the
+// samples and all outputs. The samples are evaluated in order, like an
+// episode, so recurrent nets see them with the state the earlier ones left.
+// n is left unchanged.
+func Importance(n *Net, samples [][]float64, eps float64) (importance []float64, err error) {
+	if err := checkEpsilon(eps); err != nil {
+		return nil, err
+	}
+	err = n.analyse(func() error {
+		importance = make([]float64, n.InSize())
+		count := 0
+		s := n.snapshot()
+		for _, sample := range samples {
+			grad, err := n.saliency(s, sample, eps)
+			if err != nil {
+				return err
+			}
+			for i := range grad {
+				for _, g := range grad[i] {
+					importance[i] += math.Abs(g)
+				}
+			}
+			count += n.OutSize()
+			// advance the episode
+			if _, err := n.evalFrom(s, sample); err != nil {
+				return err
+			}
+			s = n.snapshot()
+		}
+		if count > 0 {
+			for i := range importance {
+				importance[i] /= float64(count)
+			}
+		}
+		return nil
+	})
+	return importance, err
+}
+
+// Ablation replays the samples as an episode once for every input, with that
+// input replaced by value, and returns the mean absolute change of the
+// outputs compared to the unchanged episode. An input the net doesn't use
+// scores 0. n is left unchanged.
+func Ablation(n *Net, samples [][]float64, value float64) (change []float64, err error) {
+	err = n.analyse(func() error {
+		start := n.snapshot()
+		run := func(ablate int) ([][]float64, error) {
+			n.restore(start)
+			outs := make([][]float64, len(samples))
+			for k, sample := range samples {
+				if len(sample) != n.InSize() {
+					return nil, fmt.Errorf("sample %d: input size %d, expected %d", k, len(sample), n.InSize())
+				}
+				in := sample
+				if ablate >= 0 {
+					in = append([]float64(nil), sample...)
+					in[ablate] = value
+				}
+				out, err := n.Eval(in)
+				if err != nil {
+					return nil, err
+				}
+				outs[k] = out
+			}
+			return outs, nil
+		}
+
+		base, err := run(-1)
+		if err != nil {
+			return err
+		}
+		change = make([]float64, n.InSize())
+		for i := range change {
+			outs, err := run(i)
+			if err != nil {
+				return err
+			}
+			count := 0
+			for k := range outs {
+				for j := range outs[k] {
+					change[i] += math.Abs(outs[k][j] - base[k][j])
+					count++
+				}
+			}
+			if count > 0 {
+				change[i] /= float64(count)
+			}
+		}
+		return nil
+	})
+	return change, err
+}
+
+// InputSensitivity is how much the outputs of a net depend on one input
+type InputSensitivity struct {
+	Input int
+	// Saliency is the Importance of the input
+	Saliency float64
+	// Ablation is the output change when the input is zeroed
+	Ablation float64
+}
+
+// Sensitivity returns the Importance, with step SaliencyEpsilon, and the
+// Ablation to zero of every input over the samples
+func Sensitivity(n *Net, samples [][]float64) ([]InputSensitivity, error) {
+	importance, err := Importance(n, samples, SaliencyEpsilon)
+	if err != nil {
+		return nil, err
+	}
+	ablation, err := Ablation(n, samples, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]InputSensitivity, len(importance))
+	for i := range s {
+		s[i] = InputSensitivity{Input: i, Saliency: importance[i], Ablation: ablation[i]}
+	}
+	return s, nil
+}
+
+// WriteSensitivityCSV writes a row per input: input,saliency,ablation
+func WriteSensitivityCSV(w io.Writer, s []InputSensitivity) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"input", "saliency", "ablation"})
+	for _, is := range s {
+		c.Write([]string{strconv.Itoa(is.Input), formatFloat(is.Saliency), formatFloat(is.Ablation)})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteSaliencyCSV writes a row per input and output of a Saliency:
+// input,output,saliency
+func WriteSaliencyCSV(w io.Writer, saliency [][]float64) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"input", "output", "saliency"})
+	for i := range saliency {
+		for j, g := range saliency[i] {
+			c.Write([]string{strconv.Itoa(i), strconv.Itoa(j), formatFloat(g)})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
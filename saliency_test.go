@@ -0,0 +1,80 @@
+package net
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaliency(t *testing.T) {
+	// out = 2 (in0 + in1) + 3
+	n := stepNet(t, 0)
+	s, err := Saliency(n, []float64{1, 2}, SaliencyEpsilon)
+	require.NoError(t, err)
+	require.Len(t, s, 2)
+	for i := range s {
+		require.Len(t, s[i], 2)
+		for _, g := range s[i] {
+			assert.InDelta(t, 2, g, 1e-6)
+		}
+	}
+	_, err = Saliency(n, []float64{1}, SaliencyEpsilon)
+	assert.Error(t, err)
+	for _, eps := range []float64{0, -1, math.NaN()} {
+		_, err = Saliency(n, []float64{1, 2}, eps)
+		assert.Error(t, err)
+		_, err = Importance(n, [][]float64{{1, 2}}, eps)
+		assert.Error(t, err)
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, WriteSaliencyCSV(&b, s))
+	assert.Equal(t, 5, strings.Count(b.String(), "\n"))
+}
+
+func TestSensitivity(t *testing.T) {
+	n := stepNet(t, 0)
+	samples := [][]float64{{1, 2}, {3, 1}}
+	s, err := Sensitivity(n, samples)
+	require.NoError(t, err)
+	require.Len(t, s, 2)
+	assert.InDelta(t, 2, s[0].Saliency, 1e-6)
+	assert.InDelta(t, 2, s[1].Saliency, 1e-6)
+	// zeroing in0 lowers the outputs by 2 and 6, in1 by 4 and 2
+	assert.InDelta(t, 4, s[0].Ablation, 1e-9)
+	assert.InDelta(t, 3, s[1].Ablation, 1e-9)
+
+	var b bytes.Buffer
+	require.NoError(t, WriteSensitivityCSV(&b, s))
+	assert.True(t, strings.HasPrefix(b.String(), "input,saliency,ablation\n0,"))
+}
+
+func TestSensitivity_keepsState(t *testing.T) {
+	loopNet := func() *Net {
+		n := stepNet(t, 0)
+		n.addNeuron(&neuron{id: 6, layer: hiddenLayer, bias: 1, activationFunc: simple})
+		n.addSynapse(4, 6, 1)
+		n.addSynapse(6, 2, 1)
+		return n
+	}
+	a, b := loopNet(), loopNet()
+	rec := &TraceRecorder{}
+	a.SetTracer(rec)
+	for i := 0; i < 3; i++ {
+		outA, err := a.Eval([]float64{1, 1})
+		require.NoError(t, err)
+		outB, err := b.Eval([]float64{1, 1})
+		require.NoError(t, err)
+		assert.Equal(t, outB, outA)
+
+		_, err = Sensitivity(a, [][]float64{{1, 0}, {0, 1}})
+		require.NoError(t, err)
+		assert.Equal(t, b.Activations(), a.Activations())
+	}
+	// the analysis isn't traced
+	assert.Len(t, rec.Traces(), 3)
+}
@@ -0,0 +1,97 @@
+package net
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StepMode switches the net to synchronous evaluation. Every Step computes the
+// new activation of all neurons from the activations of the previous Step, so
+// recurrent connections are well defined and don't depend on the order the
+// outputs are evaluated in. Eval runs ticks Steps with the same input and
+// returns the output of the last one. A ticks of 0 switches back to the default
+// evaluation.
+func (n *Net) StepMode(ticks int) {
+	if ticks < 0 {
+		ticks = 0
+	}
+	n.ticks = ticks
+}
+
+// Step sets the input and advances every neuron by one time step
+func (n *Net) Step(input []float64) (output []float64, err error) {
+	if n == nil {
+		return nil, fmt.Errorf("Net not initialised, use the Builder")
+	}
+	if len(input) != len(n.in) {
+		return nil, fmt.Errorf("input size %d, expected %d", len(input), len(n.in))
+	}
+
+	for i := range n.in {
+		n.in[i].state = input[i]
+		n.in[i].trace(input[i], input[i])
+	}
+
+	order := n.order()
+	for _, neur := range order {
+		if neur.layer == inputLayer {
+			continue
+		}
+		var sum float64
+		for _, syn := range neur.in {
+			sum += syn.source.state * syn.weight
+		}
+		neur.next = neur.activate(sum)
+		neur.trace(sum, neur.next)
+	}
+	for _, neur := range order {
+		if neur.layer != inputLayer {
+			neur.state = neur.next
+		}
+		neur.last = neur.state
+	}
+
+	for _, neur := range n.out {
+		output = append(output, neur.state)
+	}
+	if n.tracer != nil {
+		n.tracer.Done(input, output)
+	}
+	return output, nil
+}
+
+// Reset clears the activations kept between Steps, the potentials of CTRNN
+// neurons and the state of gated neurons
+func (n *Net) Reset() {
+	for _, neur := range n.neuronStore {
+		neur.state = 0
+		neur.next = 0
+		neur.last = 0
+		neur.potential = 0
+		neur.h, neur.c = 0, 0
+	}
+}
+
+// order returns all neurons sorted by id
+func (n *Net) order() []*neuron {
+	if len(n.stepOrder) == len(n.neuronStore) {
+		return n.stepOrder
+	}
+	n.stepOrder = n.stepOrder[:0]
+	for _, neur := range n.neuronStore {
+		n.stepOrder = append(n.stepOrder, neur)
+	}
+	sort.Slice(n.stepOrder, func(i, j int) bool { return n.stepOrder[i].id < n.stepOrder[j].id })
+	return n.stepOrder
+}
+
+// Activations returns the activation of every neuron, by id, after the last
+// Eval or Step. Neurons Eval didn't reach because they don't lead to an output
+// are 0.
+func (n *Net) Activations() map[int]float64 {
+	acts := make(map[int]float64, len(n.neuronStore))
+	for id, neur := range n.neuronStore {
+		acts[id] = neur.last
+	}
+	return acts
+}
@@ -0,0 +1,95 @@
+package net
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stepNet(t *testing.T, ticks int) *Net {
+	n, err := NewBuilder().
+		ActivationFunc(simple).
+		WeightFunc(fakeWeight).
+		StepMode(ticks).
+		Build()
+	require.NoError(t, err)
+	for _, neur := range n.neuronStore {
+		neur.bias = 1
+	}
+	return n
+}
+
+func TestStep(t *testing.T) {
+	n := stepNet(t, 0)
+
+	// the output only sees the hidden layer's activation of the previous step
+	out, err := n.Step([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 1}, out)
+
+	out, err = n.Step([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{7, 7}, out)
+
+	n.Reset()
+	out, err = n.Step([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 1}, out)
+
+	_, err = n.Step([]float64{1})
+	assert.Error(t, err)
+}
+
+func TestStepMode_Eval(t *testing.T) {
+	n := stepNet(t, 2)
+	out, err := n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{7, 7}, out)
+}
+
+func TestStepMode_loop(t *testing.T) {
+	n := stepNet(t, 1)
+	n.addNeuron(&neuron{
+		id:             6,
+		layer:          hiddenLayer,
+		bias:           1,
+		activationFunc: simple,
+	})
+	n.addSynapse(4, 6, 1)
+	n.addSynapse(6, 2, 1)
+
+	// output 4 feeds back into hidden 2 through hidden 6, taking two steps to
+	// reach it, whichever output is evaluated first
+	var outs [][]float64
+	for i := 0; i < 4; i++ {
+		out, err := n.Eval([]float64{1, 1})
+		require.NoError(t, err)
+		outs = append(outs, out)
+	}
+	assert.Equal(t, [][]float64{{1, 1}, {7, 7}, {8, 8}, {9, 9}}, outs)
+}
+
+func TestActivations(t *testing.T) {
+	n := stepNet(t, 0)
+	out, err := n.Eval([]float64{1, 2})
+	require.NoError(t, err)
+	acts := n.Activations()
+	assert.Len(t, acts, len(n.neuronStore))
+	assert.Equal(t, 1.0, acts[n.in[0].id])
+	assert.Equal(t, 2.0, acts[n.in[1].id])
+	for i, neur := range n.out {
+		assert.Equal(t, out[i], acts[neur.id])
+	}
+
+	out, err = n.Step([]float64{1, 1})
+	require.NoError(t, err)
+	acts = n.Activations()
+	for i, neur := range n.out {
+		assert.Equal(t, out[i], acts[neur.id])
+	}
+	n.Reset()
+	for _, v := range n.Activations() {
+		assert.Zero(t, v)
+	}
+}
@@ -0,0 +1,188 @@
+package net
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+)
+
+// svg layout in pixels
+const (
+	svgColumn = 140
+	svgRow    = 48
+	svgMargin = 40
+	svgRadius = 13
+)
+
+// WriteSVG draws the net of dna. Inputs are on the left, outputs on the right
+// and hidden neurons in between, a column further for every synapse they are
+// away from the inputs. Synapses are blue for positive and red for negative
+// weights, thicker for larger ones. Hovering a neuron or synapse shows its
+// parameters, the elements carry data-id, data-source and data-dest
+// attributes for scripts.
+func WriteSVG(w io.Writer, dna DNA) error {
+	neurons := make(map[int]*NeuronGene)
+	for _, ng := range dna.Neurons {
+		neurons[ng.ID] = ng
+	}
+	synapses := sortedSynapses(dna.SynapseMap)
+	for _, syn := range synapses {
+		// DNAToNet creates neurons a synapse refers to as hidden
+		for _, id := range []int{syn.SourceID, syn.DestID} {
+			if _, ok := neurons[id]; !ok {
+				neurons[id] = &NeuronGene{ID: id, Layer: hiddenLayer}
+			}
+		}
+	}
+
+	column := svgColumns(neurons, synapses)
+	var cols [][]int
+	for id, c := range column {
+		for len(cols) <= c {
+			cols = append(cols, nil)
+		}
+		cols[c] = append(cols[c], id)
+	}
+	rows := 1
+	for _, ids := range cols {
+		sort.Ints(ids)
+		if len(ids) > rows {
+			rows = len(ids)
+		}
+	}
+	width := 2 * svgMargin
+	if len(cols) > 1 {
+		width += (len(cols) - 1) * svgColumn
+	}
+	height := 2*svgMargin + (rows-1)*svgRow
+	type point struct{ x, y float64 }
+	pos := make(map[int]point)
+	for c, ids := range cols {
+		// center the column vertically
+		offset := float64(rows-len(ids)) * svgRow / 2
+		for r, id := range ids {
+			pos[id] = point{
+				x: float64(svgMargin + c*svgColumn),
+				y: float64(svgMargin+r*svgRow) + offset,
+			}
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		width, height, width, height)
+	b.WriteString(`<style>.synapse{fill:none;opacity:.7}.synapse:hover,.synapse.active{opacity:1;stroke-width:5}.neuron:hover circle,.neuron.active circle{stroke-width:3}</style>` + "\n")
+
+	for _, syn := range synapses {
+		s, d := pos[syn.SourceID], pos[syn.DestID]
+		color := "#2b6cb0"
+		if syn.Weight < 0 {
+			color = "#c53030"
+		}
+		stroke := 0.5 + math.Min(math.Abs(syn.Weight), 4)
+		var path string
+		switch {
+		case syn.SourceID == syn.DestID:
+			// a loop above the neuron
+			path = fmt.Sprintf("M%.1f %.1f C%.1f %.1f %.1f %.1f %.1f %.1f",
+				s.x-svgRadius/2, s.y-svgRadius, s.x-2*svgRadius, s.y-3*svgRadius, s.x+2*svgRadius, s.y-3*svgRadius, s.x+svgRadius/2, s.y-svgRadius)
+		case d.x <= s.x:
+			// bend recurrent synapses so they don't overlap forward ones
+			path = fmt.Sprintf("M%.1f %.1f Q%.1f %.1f %.1f %.1f", s.x, s.y, (s.x+d.x)/2, (s.y+d.y)/2+svgRow, d.x, d.y)
+		default:
+			path = fmt.Sprintf("M%.1f %.1f L%.1f %.1f", s.x, s.y, d.x, d.y)
+		}
+		fmt.Fprintf(b, `<path class="synapse" data-source="%d" data-dest="%d" d="%s" stroke="%s" stroke-width="%.2f"><title>%d → %d weight %.4f</title></path>`+"\n",
+			syn.SourceID, syn.DestID, path, color, stroke, syn.SourceID, syn.DestID, syn.Weight)
+	}
+
+	ids := make([]int, 0, len(neurons))
+	for id := range neurons {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		ng := neurons[id]
+		p := pos[id]
+		fill := "#e2e8f0"
+		layer := "hidden"
+		switch ng.Layer {
+		case inputLayer:
+			fill, layer = "#c6f6d5", "input"
+		case outputLayer:
+			fill, layer = "#fefcbf", "output"
+		}
+		title := fmt.Sprintf("neuron %d, %s %s\nbias %.4f", id, KindName(ng.Kind), layer, ng.Bias)
+		if ng.Kind == KindCTRNN {
+			title += fmt.Sprintf("\ntau %.4f", ng.Tau)
+		}
+		fmt.Fprintf(b, `<g class="neuron" data-id="%d"><circle cx="%.1f" cy="%.1f" r="%d" fill="%s" stroke="#2d3748"/><text x="%.1f" y="%.1f" text-anchor="middle">%d</text><title>%s</title></g>`+"\n",
+			id, p.x, p.y, svgRadius, fill, p.x, p.y+4, id, html.EscapeString(title))
+	}
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
+
+// svgColumns returns the column of every neuron: 0 for inputs, the last one
+// for outputs and the shortest distance from an input for hidden neurons
+func svgColumns(neurons map[int]*NeuronGene, synapses []SynapseGene) map[int]int {
+	next := make(map[int][]int)
+	for _, syn := range synapses {
+		next[syn.SourceID] = append(next[syn.SourceID], syn.DestID)
+	}
+	depth := make(map[int]int)
+	var queue []int
+	for id, ng := range neurons {
+		if ng.Layer == inputLayer {
+			depth[id] = 0
+			queue = append(queue, id)
+		}
+	}
+	sort.Ints(queue)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, dest := range next[id] {
+			if _, ok := depth[dest]; !ok {
+				depth[dest] = depth[id] + 1
+				queue = append(queue, dest)
+			}
+		}
+	}
+
+	// unreachable hidden neurons go after the deepest reachable ones
+	deepest := 0
+	for id, d := range depth {
+		if neurons[id].Layer == hiddenLayer && d > deepest {
+			deepest = d
+		}
+	}
+	column := make(map[int]int)
+	for id, ng := range neurons {
+		switch ng.Layer {
+		case inputLayer:
+			column[id] = 0
+		case hiddenLayer:
+			d, ok := depth[id]
+			if !ok {
+				d = deepest + 1
+			}
+			column[id] = d
+		}
+	}
+	last := 1
+	for _, c := range column {
+		if c+1 > last {
+			last = c + 1
+		}
+	}
+	for id, ng := range neurons {
+		if ng.Layer == outputLayer {
+			column[id] = last
+		}
+	}
+	return column
+}
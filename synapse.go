@@ -12,6 +12,14 @@ func (s *synapse) eval(id int) signal {
 		s.source.memory != nil &&
 		s.source.memory.id != id {
 		//		fmt.Println("neuron source has memory, returning:", s.source.id, s.source.memory)
+		if n := s.source.net; n != nil && n.tracer != nil {
+			n.tracer.Memory(MemoryRead{
+				SourceID: s.source.id,
+				DestID:   s.destination.id,
+				Value:    s.source.memory.v,
+				Weight:   s.weight,
+			})
+		}
 		return signal{v: s.source.memory.v * s.weight, id: s.source.memory.id}
 	}
 	if s.source.visited {
@@ -0,0 +1,160 @@
+package net
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Tracer is told what every neuron computes during Eval and Step. In step mode
+// an Eval is several Steps, each of them traced on its own.
+type Tracer interface {
+	// Neuron is called for every neuron computed, and for every input neuron
+	// with its input
+	Neuron(t NeuronTrace)
+	// Memory is called when a recurrent synapse reads the activation its
+	// source had in an earlier Eval. Step mode reads all activations from the
+	// previous Step and doesn't call it.
+	Memory(m MemoryRead)
+	// Done ends an Eval or Step
+	Done(input, output []float64)
+}
+
+// NeuronTrace is the computation of one neuron. Sum is the weighted sum of
+// its incoming synapses, before the bias and activation.
+type NeuronTrace struct {
+	ID     int
+	Layer  int
+	Kind   int
+	Sum    float64
+	Bias   float64
+	Output float64
+}
+
+// MemoryRead is a recurrent synapse reading the remembered activation of its
+// source, Value is before the weight
+type MemoryRead struct {
+	SourceID int
+	DestID   int
+	Value    float64
+	Weight   float64
+}
+
+// SetTracer traces every following Eval and Step with t, nil stops tracing
+func (n *Net) SetTracer(t Tracer) {
+	n.tracer = t
+}
+
+func (n *neuron) trace(sum, output float64) {
+	if n.net == nil || n.net.tracer == nil {
+		return
+	}
+	n.net.tracer.Neuron(NeuronTrace{
+		ID:     n.id,
+		Layer:  int(n.layer),
+		Kind:   int(n.kind),
+		Sum:    sum,
+		Bias:   n.bias,
+		Output: output,
+	})
+}
+
+// Trace is a traced Eval or Step, Neurons are sorted by id
+type Trace struct {
+	Input   []float64
+	Output  []float64
+	Neurons []NeuronTrace
+	Memory  []MemoryRead `json:",omitempty"`
+}
+
+// Neuron returns the trace of neuron id, false if it wasn't computed
+func (t Trace) Neuron(id int) (NeuronTrace, bool) {
+	i := sort.Search(len(t.Neurons), func(i int) bool { return t.Neurons[i].ID >= id })
+	if i < len(t.Neurons) && t.Neurons[i].ID == id {
+		return t.Neurons[i], true
+	}
+	return NeuronTrace{}, false
+}
+
+// TraceRecorder is a Tracer that keeps the traces of an episode
+type TraceRecorder struct {
+	// Limit is the maximum amount of traces kept, older ones are dropped. 0
+	// keeps everything.
+	Limit int
+
+	traces  []Trace
+	current Trace
+}
+
+// Neuron records t in the current trace
+func (r *TraceRecorder) Neuron(t NeuronTrace) {
+	r.current.Neurons = append(r.current.Neurons, t)
+}
+
+// Memory records m in the current trace
+func (r *TraceRecorder) Memory(m MemoryRead) {
+	r.current.Memory = append(r.current.Memory, m)
+}
+
+// Done finishes the current trace
+func (r *TraceRecorder) Done(input, output []float64) {
+	t := r.current
+	r.current = Trace{}
+	t.Input = append([]float64(nil), input...)
+	t.Output = append([]float64(nil), output...)
+	sort.SliceStable(t.Neurons, func(i, j int) bool { return t.Neurons[i].ID < t.Neurons[j].ID })
+	r.traces = append(r.traces, t)
+	if r.Limit > 0 && len(r.traces) > r.Limit {
+		r.traces = r.traces[len(r.traces)-r.Limit:]
+	}
+}
+
+// Traces returns the recorded traces, oldest first
+func (r *TraceRecorder) Traces() []Trace {
+	return r.traces
+}
+
+// Reset drops all traces, for a new episode
+func (r *TraceRecorder) Reset() {
+	r.traces = nil
+	r.current = Trace{}
+}
+
+// Outputs returns the output of neuron id in every trace, NaN where it
+// wasn't computed
+func (r *TraceRecorder) Outputs(id int) []float64 {
+	out := make([]float64, len(r.traces))
+	for i, t := range r.traces {
+		nt, ok := t.Neuron(id)
+		if !ok {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = nt.Output
+	}
+	return out
+}
+
+// WriteCSV writes a row for every neuron in every trace:
+// step,id,layer,kind,sum,bias,output
+func (r *TraceRecorder) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"step", "id", "layer", "kind", "sum", "bias", "output"})
+	for step, t := range r.traces {
+		for _, nt := range t.Neurons {
+			c.Write([]string{
+				strconv.Itoa(step),
+				strconv.Itoa(nt.ID),
+				strconv.Itoa(nt.Layer),
+				KindName(nt.Kind),
+				formatFloat(nt.Sum),
+				formatFloat(nt.Bias),
+				formatFloat(nt.Output),
+			})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
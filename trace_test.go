@@ -0,0 +1,71 @@
+package net
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	n := stepNet(t, 0)
+	rec := &TraceRecorder{}
+	n.SetTracer(rec)
+
+	out, err := n.Eval([]float64{1, 2})
+	require.NoError(t, err)
+	traces := rec.Traces()
+	require.Len(t, traces, 1)
+	assert.Equal(t, []float64{1, 2}, traces[0].Input)
+	assert.Equal(t, out, traces[0].Output)
+	require.Len(t, traces[0].Neurons, len(n.neuronStore))
+	hidden, ok := traces[0].Neuron(n.hidden[0].id)
+	require.True(t, ok)
+	assert.Equal(t, NeuronTrace{ID: n.hidden[0].id, Layer: hiddenLayer, Sum: 3, Bias: 1, Output: 4}, hidden)
+	in, _ := traces[0].Neuron(n.in[1].id)
+	assert.Equal(t, 2.0, in.Output)
+
+	// step mode traces every step
+	n.StepMode(2)
+	_, err = n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Len(t, rec.Traces(), 3)
+	assert.Equal(t, []float64{9, 1, 7}, rec.Outputs(n.out[0].id))
+
+	rec.Reset()
+	n.SetTracer(nil)
+	_, err = n.Eval([]float64{1, 1})
+	require.NoError(t, err)
+	assert.Empty(t, rec.Traces())
+}
+
+func TestTracer_memory(t *testing.T) {
+	n := stepNet(t, 0)
+	n.addNeuron(&neuron{id: 6, layer: hiddenLayer, bias: 1, activationFunc: simple})
+	n.addSynapse(4, 6, 1)
+	n.addSynapse(6, 2, 1)
+	rec := &TraceRecorder{Limit: 2}
+	n.SetTracer(rec)
+
+	for i := 0; i < 3; i++ {
+		_, err := n.Eval([]float64{1, 1})
+		require.NoError(t, err)
+	}
+	traces := rec.Traces()
+	require.Len(t, traces, 2)
+	require.NotEmpty(t, traces[1].Memory)
+	// the loop 2 -> 4 -> 6 -> 2 is cut where it was detected
+	m := traces[1].Memory[0]
+	assert.Contains(t, [][2]int{{2, 4}, {4, 6}, {6, 2}}, [2]int{m.SourceID, m.DestID})
+	assert.False(t, math.IsNaN(rec.Outputs(6)[1]))
+	assert.True(t, math.IsNaN(rec.Outputs(42)[0]))
+
+	var b bytes.Buffer
+	require.NoError(t, rec.WriteCSV(&b))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, "step,id,layer,kind,sum,bias,output", lines[0])
+	assert.Len(t, lines, 1+len(traces[0].Neurons)+len(traces[1].Neurons))
+}
@@ -0,0 +1,80 @@
+package net
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Validate checks that dna makes a deterministic, working net. Unlike
+// DNAToNet it reports every problem, not just the first, and also rejects
+// synapses to neurons the dna doesn't have, which DNAToNet creates with a
+// random bias.
+func (dna DNA) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	finite := func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
+
+	ids := make(map[int]bool, len(dna.Neurons))
+	var inputs, outputs int
+	for _, ng := range dna.Neurons {
+		if ng == nil {
+			add("nil neuron")
+			continue
+		}
+		if ids[ng.ID] {
+			add("neuron %d: duplicate id", ng.ID)
+		}
+		ids[ng.ID] = true
+		switch ng.Layer {
+		case inputLayer:
+			inputs++
+		case outputLayer:
+			outputs++
+		case hiddenLayer:
+		default:
+			add("neuron %d: unknown layer %d", ng.ID, ng.Layer)
+		}
+		if ng.Kind < KindStandard || ng.Kind > KindLSTM {
+			add("neuron %d: unknown kind %d", ng.ID, ng.Kind)
+		} else if len(ng.Gates) != gateCount(ng.Kind) {
+			add("neuron %d: %d gate parameters, expected %d", ng.ID, len(ng.Gates), gateCount(ng.Kind))
+		}
+		if _, ok := activationFuncs[ng.Activation]; !ok {
+			add("neuron %d: unknown activation %d", ng.ID, ng.Activation)
+		}
+		if !finite(ng.Bias) || !finite(ng.Tau) {
+			add("neuron %d: bias or tau isn't a number", ng.ID)
+		}
+		for _, g := range ng.Gates {
+			if !finite(g) {
+				add("neuron %d: gate parameter isn't a number", ng.ID)
+				break
+			}
+		}
+	}
+	if inputs == 0 {
+		add("no input neurons")
+	}
+	if outputs == 0 {
+		add("no output neurons")
+	}
+
+	for _, syn := range sortedSynapses(dna.SynapseMap) {
+		if !ids[syn.SourceID] {
+			add("synapse %d -> %d: unknown source", syn.SourceID, syn.DestID)
+		}
+		if !ids[syn.DestID] {
+			add("synapse %d -> %d: unknown destination", syn.SourceID, syn.DestID)
+		}
+		if !finite(syn.Weight) {
+			add("synapse %d -> %d: weight isn't a number", syn.SourceID, syn.DestID)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
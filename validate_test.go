@@ -0,0 +1,27 @@
+package net
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	n, err := NewBuilder().Size(2, 2, 1).HiddenKind(KindGRU).Build()
+	require.NoError(t, err)
+	dna := NetToDna(n)
+	require.NoError(t, dna.Validate())
+
+	bad := dna.Clone()
+	bad.Neurons = append(bad.Neurons, &NeuronGene{ID: bad.Neurons[0].ID, Layer: 7, Kind: KindLSTM, Activation: 99, Bias: math.NaN()})
+	bad.SynapseMap[SynapseGene{SourceID: 0, DestID: 1000, Weight: math.Inf(1)}] = struct{}{}
+	err = bad.Validate()
+	require.Error(t, err)
+	for _, problem := range []string{"duplicate id", "unknown layer", "gate parameters", "unknown activation", "bias", "unknown destination", "weight"} {
+		assert.Contains(t, err.Error(), problem)
+	}
+
+	assert.Error(t, DNA{}.Validate())
+}